@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/consistency"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+)
+
+// checkCommand checks the family graph for impossible data and exits with 1 if any errors are found, e.g.
+// `dbsetup check --rules parent-cycle,too-many-parents`
+func checkCommand(args []string) {
+	config := consistency.DefaultConfig()
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	dbKuzuPath := flags.String("db-kuzu-path", DB_KUZU_PATH, "Path to kuzu database file")
+	rules := flags.String("rules", "", "Comma separated rules to check, all of "+strings.Join(consistency.RuleNames(), ", ")+" by default")
+	minParentAge := flags.Int("min-parent-age", int(config.MinParentAge), "Age a parent must have reached at the birth of the child")
+	maxLifespan := flags.Int("max-lifespan", int(config.MaxLifespan), "Age above which a lifespan is reported as implausible")
+	_ = flags.Parse(args)
+
+	if len(*rules) > 0 {
+		config.Rules = strings.Split(*rules, ",")
+	}
+	config.MinParentAge, config.MaxLifespan = int32(*minParentAge), int32(*maxLifespan)
+
+	kuzuDb, pool := db.ConnectToKuzu(*dbKuzuPath, 1, time.Minute)
+	defer kuzuDb.Close()
+	defer pool.Close()
+
+	log.Println("[check] Checking consistency of the family graph...")
+	report, err := service.NewFamilyTreeService(pool, nil, nil, nil).CheckConsistency(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, issue := range report.Issues {
+		log.Printf("[check] %s (%s): %s", strings.ToUpper(issue.Severity), issue.Rule, issue.Message)
+	}
+	log.Printf("[check] Found %d errors and %d warnings", report.Errors, report.Warnings)
+
+	if report.Errors > 0 {
+		pool.Close()
+		kuzuDb.Close()
+		os.Exit(1)
+	}
+}
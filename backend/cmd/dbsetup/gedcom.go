@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+)
+
+// importGedcomCommand imports a GEDCOM file into an already migrated kuzu database, e.g.
+// `dbsetup import-gedcom --dry-run ./family.ged`
+func importGedcomCommand(args []string) {
+	flags := flag.NewFlagSet("import-gedcom", flag.ExitOnError)
+	dbKuzuPath := flags.String("db-kuzu-path", DB_KUZU_PATH, "Path to kuzu database file")
+	dryRun := flags.Bool("dry-run", false, "Only report what would be created")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: dbsetup import-gedcom [--db-kuzu-path path] [--dry-run] file.ged")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	kuzuDb, pool := db.ConnectToKuzu(*dbKuzuPath, 1, time.Minute)
+	defer kuzuDb.Close()
+	defer pool.Close()
+
+	log.Println("[gedcom] Importing " + flags.Arg(0) + "...")
+	report, err := service.NewFamilyTreeService(pool, nil, nil, nil).ImportGedcom(file, *dryRun, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, warning := range report.Warnings {
+		log.Println("[gedcom] Warning: " + warning)
+	}
+	if report.DryRun {
+		log.Printf("[gedcom] Would create %d persons, %d parent relations and %d marriage relations",
+			len(report.Persons), len(report.ParentRelations), len(report.MarriageRelations))
+	} else {
+		log.Printf("[gedcom] Created %d persons, %d parent relations and %d marriage relations",
+			len(report.Persons), len(report.ParentRelations), len(report.MarriageRelations))
+	}
+}
@@ -21,13 +21,32 @@ const DB_SQLITE_PATH string = "./example.sqlite"
 const DATA_PATH_PREFIX string = "../../../data"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import-gedcom":
+			importGedcomCommand(os.Args[2:])
+			return
+		case "generate-synthetic":
+			generateSyntheticCommand(os.Args[2:])
+			return
+		case "check":
+			checkCommand(os.Args[2:])
+			return
+		}
+	}
+
 	dbKuzuPath := flag.String("db-kuzu-path", DB_KUZU_PATH, "Path to kuzu database file")
 	dbSqlitePath := flag.String("db-sqlite-path", DB_SQLITE_PATH, "Path to sqlite database file")
 	dataPathPrefix := flag.String("data-path-prefix", DATA_PATH_PREFIX, "Path prefix for data")
+	rebuildSiblings := flag.Bool("rebuild-siblings", false, "Rebuild all derived sibling relations from the parent relations")
 	flag.Parse()
 
 	initKuzu(*dbKuzuPath, *dataPathPrefix)
 	initSqlite(*dbSqlitePath, *dataPathPrefix)
+
+	if *rebuildSiblings {
+		rebuildSiblingRelations(*dbKuzuPath)
+	}
 }
 
 func publicOrPrivateData(dataPath string) string {
@@ -0,0 +1,11 @@
+CREATE NODE TABLE Event (
+    id UUID PRIMARY KEY,
+    event_type STRING,
+    date_year INT,
+    date_month INT,
+    date_day INT,
+    place STRING,
+    description STRING
+);
+
+CREATE REL TABLE HAS_EVENT(FROM Person TO Event);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE NODE TABLE Place (
+    id UUID PRIMARY KEY,
+    name STRING,
+    historical_names STRING[],
+    latitude DOUBLE,
+    longitude DOUBLE
+);
+
+CREATE REL TABLE LOCATED_IN(FROM Place TO Place);
+
+CREATE REL TABLE TOOK_PLACE_AT(FROM Event TO Place);
\ No newline at end of file
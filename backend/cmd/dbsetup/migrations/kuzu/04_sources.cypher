@@ -0,0 +1,25 @@
+CREATE NODE TABLE Source (
+    id UUID PRIMARY KEY,
+    source_type STRING,
+    title STRING,
+    author STRING,
+    repository STRING,
+    url STRING
+);
+
+CREATE NODE TABLE Citation (
+    id UUID PRIMARY KEY,
+    subject_type STRING,
+    subject_id STRING,
+    page STRING,
+    quality INT,
+    transcription STRING
+);
+
+CREATE REL TABLE CITES(FROM Citation TO Source);
+
+CREATE REL TABLE SUPPORTS_PERSON(FROM Citation TO Person);
+
+CREATE REL TABLE SUPPORTS_EVENT(FROM Citation TO Event);
+
+CREATE REL TABLE SUPPORTS_RELATION(FROM Citation TO Person);
\ No newline at end of file
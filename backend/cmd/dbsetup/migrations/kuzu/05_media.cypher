@@ -0,0 +1,15 @@
+CREATE NODE TABLE Media (
+    id UUID PRIMARY KEY,
+    hash STRING,
+    file_name STRING,
+    content_type STRING,
+    size INT64,
+    width INT,
+    height INT,
+    has_thumbnail BOOLEAN,
+    caption STRING
+);
+
+CREATE REL TABLE ATTACHED_TO_PERSON(FROM Media TO Person);
+
+CREATE REL TABLE ATTACHED_TO_EVENT(FROM Media TO Event);
\ No newline at end of file
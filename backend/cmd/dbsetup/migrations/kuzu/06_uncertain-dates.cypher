@@ -0,0 +1,19 @@
+ALTER TABLE Person ADD birth_date_qualifier STRING;
+
+ALTER TABLE Person ADD birth_date_to_year INT;
+
+ALTER TABLE Person ADD birth_date_to_month INT;
+
+ALTER TABLE Person ADD birth_date_to_day INT;
+
+ALTER TABLE Person ADD birth_date_calendar STRING;
+
+ALTER TABLE Person ADD death_date_qualifier STRING;
+
+ALTER TABLE Person ADD death_date_to_year INT;
+
+ALTER TABLE Person ADD death_date_to_month INT;
+
+ALTER TABLE Person ADD death_date_to_day INT;
+
+ALTER TABLE Person ADD death_date_calendar STRING;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN is_disabled INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_name_index ON users(name);
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    family TEXT NOT NULL,
+    creation_timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_index ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_index ON refresh_tokens(family);
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Failed logins are tracked per key, which is either 'user:<name>' or 'ip:<address>'
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure DATETIME NOT NULL,
+    locked_until DATETIME
+);
\ No newline at end of file
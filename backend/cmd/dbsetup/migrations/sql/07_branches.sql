@@ -0,0 +1,12 @@
+-- A branch consists of its root person and all descendants, which can be granted to users beyond their own node
+CREATE TABLE IF NOT EXISTS branches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    root_node TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_branches (
+    user_id INTEGER NOT NULL,
+    branch_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, branch_id)
+);
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- The public showcase, which anonymous visitors may see, consists of single persons and whole branches
+ALTER TABLE branches ADD COLUMN is_public INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS public_persons (
+    node TEXT PRIMARY KEY
+);
+
+-- Keeps the showcase of the example data, which was previously hardcoded
+INSERT INTO public_persons (node) VALUES
+    ('01994d49-826f-76ac-aead-5bdf618ef2c5'),
+    ('01994d49-8270-755a-bbb1-310ed0140db2'),
+    ('01994d49-8270-755a-bbb1-37d4fda28301'),
+    ('01994d49-8270-755a-bbb1-390cb53d432b'),
+    ('01994d49-8270-755a-bbb1-3d7704871402'),
+    ('01994d49-8270-755a-bbb1-42bf3f1fd9ba'),
+    ('01994d49-8270-755a-bbb1-4eac575bec0f'),
+    ('01994d49-8270-755a-bbb1-50baa8e31af1'),
+    ('01994d49-8270-755a-bbb1-5435c2658136'),
+    ('01994d49-8270-755a-bbb1-5a0ee242a486'),
+    ('01994d49-8270-755a-bbb1-5cb5aaa32746'),
+    ('01994d49-8270-755a-bbb1-616f585516a3'),
+    ('01994d49-8270-755a-bbb1-65adc88896bb'),
+    ('01994d49-8270-755a-bbb1-68a322cd4491')
+ON CONFLICT DO NOTHING;
\ No newline at end of file
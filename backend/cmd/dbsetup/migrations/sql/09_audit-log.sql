@@ -0,0 +1,15 @@
+-- Every data-changing or security-relevant action, where before and after hold the affected values as JSON
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp DATETIME NOT NULL,
+    username TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before TEXT,
+    after TEXT
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_timestamp_index ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS audit_log_username_index ON audit_log(username);
+CREATE INDEX IF NOT EXISTS audit_log_entity_index ON audit_log(entity_type, entity_id);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- Every version of persons and relations, where data is NULL once the entity has been deleted
+CREATE TABLE IF NOT EXISTS revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    timestamp DATETIME NOT NULL,
+    username TEXT NOT NULL,
+    data TEXT,
+    UNIQUE (entity_type, entity_id, version)
+);
\ No newline at end of file
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+)
+
+func rebuildSiblingRelations(dbPath string) {
+	log.Println("[kuzu] Rebuilding sibling relations...")
+	kuzuDb, pool := db.ConnectToKuzu(dbPath, 1, time.Minute)
+	defer kuzuDb.Close()
+	defer pool.Close()
+
+	if err := db.RebuildAllSiblingRelations(pool); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("[kuzu] Rebuilt sibling relations")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+var syntheticFirstNames = map[string][]string{
+	"m": {"Johann", "Franz", "Josef", "Karl", "Anton", "Georg", "Michael", "Thomas", "Peter", "Paul", "Stefan", "Lukas"},
+	"f": {"Maria", "Anna", "Theresia", "Elisabeth", "Katharina", "Johanna", "Barbara", "Sophie", "Julia", "Lena", "Eva", "Rosa"},
+}
+
+var syntheticLastNames = []string{
+	"Gruber", "Huber", "Bauer", "Wagner", "Müller", "Pichler", "Steiner", "Moser", "Mayer", "Hofer", "Leitner", "Berger",
+	"Fuchs", "Eder", "Fischer", "Schmid", "Winkler", "Weber", "Schwarz", "Maier", "Schneider", "Reiter", "Mayr", "Schmidt",
+}
+
+type syntheticPerson struct {
+	id        uuid.UUID
+	firstName string
+	lastName  string
+	birthName string
+	gender    string
+	birthYear int
+}
+
+type syntheticGraph struct {
+	rng       *rand.Rand
+	persons   [][]string
+	parents   [][]string
+	marriages [][]string
+}
+
+// generateSyntheticCommand writes a synthetic family graph as CSV files, which can then be loaded by the regular
+// setup via --data-path-prefix, e.g. `dbsetup generate-synthetic --persons 50000 --out ./synthetic`
+func generateSyntheticCommand(args []string) {
+	flags := flag.NewFlagSet("generate-synthetic", flag.ExitOnError)
+	outPath := flags.String("out", "./synthetic", "Directory to write the CSV files to")
+	personCount := flags.Int("persons", 50000, "Approximate number of persons to generate")
+	seed := flags.Uint64("seed", 1, "Seed for the random generator, so that the structure of the graph is reproducible")
+	_ = flags.Parse(args)
+
+	if err := os.MkdirAll(*outPath, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("[synthetic] Generating about %d persons...", *personCount)
+	g := &syntheticGraph{rng: rand.New(rand.NewPCG(*seed, *seed))}
+	root := g.generate(*personCount)
+
+	g.write(path.Join(*outPath, "people.csv"),
+		[]string{"id", "first_name", "middle_name", "last_name", "birth_name", "gender", "dead",
+			"birth_date_year", "birth_date_month", "birth_date_day", "death_date_year", "death_date_month", "death_date_day"},
+		g.persons)
+	g.write(path.Join(*outPath, "parent-relations.csv"), []string{"parent", "child"}, g.parents)
+	g.write(path.Join(*outPath, "marriage-relations.csv"),
+		[]string{"person1", "person2", "since_year", "since_month", "since_day", "until_year", "until_month", "until_day"},
+		g.marriages)
+	g.write(path.Join(*outPath, "users.csv"), []string{"name", "password", "role", "node"},
+		[][]string{{"admin", "test", "admin", root.String()}})
+
+	log.Printf("[synthetic] Generated %d persons, %d parent relations and %d marriage relations in %s",
+		len(g.persons), len(g.parents), len(g.marriages), *outPath)
+}
+
+// generate simulates generations of couples, where most persons marry either within their generation or someone
+// without known ancestors, and every couple has a few children. It returns the id of the first person.
+func (g *syntheticGraph) generate(personCount int) uuid.UUID {
+	founderCount := max(personCount/100, 2)
+	generation := make([]*syntheticPerson, 0, founderCount)
+	for range founderCount {
+		generation = append(generation, g.newPerson(1800+g.rng.IntN(10), "", ""))
+	}
+	root := generation[0].id
+
+	for len(g.persons) < personCount {
+		next := make([]*syntheticPerson, 0)
+		g.rng.Shuffle(len(generation), func(i, j int) {
+			generation[i], generation[j] = generation[j], generation[i]
+		})
+
+		unmarried := map[string][]*syntheticPerson{"m": {}, "f": {}}
+		for _, person := range generation {
+			if g.rng.Float64() < 0.85 {
+				unmarried[person.gender] = append(unmarried[person.gender], person)
+			}
+		}
+
+		for len(unmarried["m"]) > 0 || len(unmarried["f"]) > 0 {
+			husband, wife := g.pop(unmarried, "m"), g.pop(unmarried, "f")
+			if husband == nil {
+				husband = g.newSpouse(wife, "m")
+			}
+			if wife == nil {
+				wife = g.newSpouse(husband, "f")
+			}
+
+			marriageYear := max(husband.birthYear, wife.birthYear) + 20 + g.rng.IntN(10)
+			g.marriages = append(g.marriages, []string{husband.id.String(), wife.id.String(),
+				strconv.Itoa(marriageYear), strconv.Itoa(1 + g.rng.IntN(12)), strconv.Itoa(1 + g.rng.IntN(28)), "", "", ""})
+
+			for i := range g.rng.IntN(5) {
+				child := g.newPerson(marriageYear+1+2*i, husband.lastName, "")
+				g.parents = append(g.parents,
+					[]string{husband.id.String(), child.id.String()},
+					[]string{wife.id.String(), child.id.String()})
+				next = append(next, child)
+			}
+		}
+
+		// Keep the simulation going even if a generation happens to die out
+		if len(next) == 0 {
+			next = append(next, g.newPerson(generation[0].birthYear+25, "", ""))
+		}
+		generation = next
+	}
+
+	return root
+}
+
+func (g *syntheticGraph) pop(unmarried map[string][]*syntheticPerson, gender string) *syntheticPerson {
+	if len(unmarried[gender]) == 0 {
+		return nil
+	}
+	person := unmarried[gender][0]
+	unmarried[gender] = unmarried[gender][1:]
+	return person
+}
+
+func (g *syntheticGraph) newSpouse(partner *syntheticPerson, gender string) *syntheticPerson {
+	return g.newPerson(partner.birthYear-3+g.rng.IntN(7), "", gender)
+}
+
+// newPerson creates a person with a random gender and last name, unless they are given
+func (g *syntheticGraph) newPerson(birthYear int, lastName, gender string) *syntheticPerson {
+	id, err := uuid.NewV7()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(gender) == 0 {
+		gender = "m"
+		if g.rng.IntN(2) == 0 {
+			gender = "f"
+		}
+	}
+	if len(lastName) == 0 {
+		lastName = syntheticLastNames[g.rng.IntN(len(syntheticLastNames))]
+	}
+	person := &syntheticPerson{
+		id:        id,
+		firstName: syntheticFirstNames[gender][g.rng.IntN(len(syntheticFirstNames[gender]))],
+		lastName:  lastName,
+		birthName: lastName,
+		gender:    gender,
+		birthYear: birthYear,
+	}
+
+	isDead := birthYear < 1940
+	row := []string{id.String(), person.firstName, "", person.lastName, person.birthName, gender,
+		strconv.FormatBool(isDead), strconv.Itoa(birthYear), strconv.Itoa(1 + g.rng.IntN(12)), strconv.Itoa(1 + g.rng.IntN(28)),
+		"", "", ""}
+	if isDead {
+		row[10] = strconv.Itoa(birthYear + 40 + g.rng.IntN(50))
+	}
+	g.persons = append(g.persons, row)
+
+	return person
+}
+
+func (g *syntheticGraph) write(filePath string, header []string, rows [][]string) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.Write(header); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		log.Fatal(err)
+	}
+}
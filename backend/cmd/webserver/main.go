@@ -2,23 +2,53 @@ package main
 
 import (
 	"flag"
+	"log"
+	"path/filepath"
+	"time"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal"
+	"github.com/Sakrafux/family-tree-app/backend/internal/security"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 )
 
 const DB_KUZU_PATH string = "../dbsetup/example.kuzu"
 const DB_SQLITE_PATH string = "../dbsetup/example.sqlite"
 const PORT string = ":8080"
+const KUZU_POOL_SIZE int = 8
+const KUZU_POOL_TIMEOUT time.Duration = 5 * time.Second
+const PRIVACY_MIN_ROLE string = "admin"
+const USER_VISIBLE_DISTANCE int = 4
 
 func main() {
 	dbKuzuPath := flag.String("db-kuzu-path", DB_KUZU_PATH, "Path to kuzu database file")
 	dbSqlitePath := flag.String("db-sqlite-path", DB_SQLITE_PATH, "Path to sqlite database file")
+	mediaPath := flag.String("media-path", "", "Directory for uploaded media, defaults to 'media' next to the kuzu database")
+	kuzuPoolSize := flag.Int("kuzu-pool-size", KUZU_POOL_SIZE, "Number of concurrent kuzu connections")
+	kuzuPoolTimeout := flag.Duration("kuzu-pool-timeout", KUZU_POOL_TIMEOUT, "Maximum wait for a free kuzu connection")
+	privacyMinRole := flag.String("privacy-min-role", PRIVACY_MIN_ROLE, "Lowest role which may see the details of living persons")
+	userVisibleDistance := flag.Int("user-visible-distance", USER_VISIBLE_DISTANCE, "Maximum distance from their own node within which non-admin users see persons")
+	trustedProxy := flag.Bool("trusted-proxy", false, "Key the login lockout by the last X-Forwarded-For entry, only for servers exclusively reachable through a reverse proxy")
 	flag.Parse()
 
+	if !security.IsValidRole(*privacyMinRole) {
+		log.Fatalf("Unknown role '%s' for --privacy-min-role", *privacyMinRole)
+	}
+	if len(*mediaPath) == 0 {
+		*mediaPath = filepath.Join(filepath.Dir(*dbKuzuPath), "media")
+	}
+
 	app := internal.NewApp(&internal.AppConfig{
-		DB_KUZU_PATH:   *dbKuzuPath,
-		DB_SQLITE_PATH: *dbSqlitePath,
-		PORT:           PORT,
+		DB_KUZU_PATH:      *dbKuzuPath,
+		DB_SQLITE_PATH:    *dbSqlitePath,
+		MEDIA_PATH:        *mediaPath,
+		PORT:              PORT,
+		KUZU_POOL_SIZE:    *kuzuPoolSize,
+		KUZU_POOL_TIMEOUT: *kuzuPoolTimeout,
+		ACCESS: service.AccessConfig{
+			PrivacyMinRole:      *privacyMinRole,
+			UserVisibleDistance: *userVisibleDistance,
+		},
+		TRUSTED_PROXY: *trustedProxy,
 	})
 	app.Start()
 }
@@ -3,29 +3,39 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
 	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/media"
 	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 	"github.com/google/uuid"
-	"github.com/kuzudb/go-kuzu"
 )
 
 type Handler struct {
-	conn              *kuzu.Connection
 	familyTreeService *service.FamilyTreeService
 	feedbackService   *service.FeedbackService
 	securityService   *service.SecurityService
+	userService       *service.UserService
+	branchService     *service.BranchService
+	auditService      *service.AuditService
+	mediaService      *service.MediaService
 }
 
-func NewHandler(kuzuConn *kuzu.Connection, sqlDb *sql.DB) *Handler {
+func NewHandler(kuzuPool *db.KuzuPool, sqlDb *sql.DB, accessConfig service.AccessConfig, mediaPath string) *Handler {
+	access := service.NewAccessPolicy(sqlDb, kuzuPool, accessConfig)
+	audit := service.NewAuditLog(sqlDb)
 	return &Handler{
-		conn:              kuzuConn,
-		familyTreeService: service.NewFamilyTreeService(kuzuConn),
-		feedbackService:   service.NewFeedbackService(sqlDb),
-		securityService:   service.NewSecurityService(sqlDb),
+		familyTreeService: service.NewFamilyTreeService(kuzuPool, access, audit, service.NewHistory(sqlDb)),
+		branchService:     service.NewBranchService(sqlDb, kuzuPool, audit),
+		feedbackService:   service.NewFeedbackService(sqlDb, audit),
+		securityService:   service.NewSecurityService(sqlDb, audit),
+		userService:       service.NewUserService(sqlDb, kuzuPool, audit),
+		auditService:      service.NewAuditService(sqlDb),
+		mediaService:      service.NewMediaService(kuzuPool, media.NewStore(mediaPath), access, audit),
 	}
 }
 
@@ -44,11 +54,216 @@ func (h *Handler) GetFamilyTree(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err = allowDummyDataForUnauthorized(r, r.PathValue("id")); err != nil {
+	data, err := h.familyTreeService.GetFamilyTree(id, distance, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetFamilyTreeGedcom(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	distance := math.MaxInt
+	if r.URL.Query().Has("distance") {
+		distance, err = strconv.Atoi(r.URL.Query().Get("distance"))
+		if err != nil {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	data, err := h.familyTreeService.ExportGedcom(id, distance, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-gedcom; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.ged\"", id))
+	_, _ = w.Write(data)
+}
+
+func (h *Handler) GetAncestorTree(w http.ResponseWriter, r *http.Request) {
+	h.getGenerationTree(w, r, h.familyTreeService.GetAncestorTree)
+}
+
+func (h *Handler) GetDescendantTree(w http.ResponseWriter, r *http.Request) {
+	h.getGenerationTree(w, r, h.familyTreeService.GetDescendantTree)
+}
+
+func (h *Handler) getGenerationTree(w http.ResponseWriter, r *http.Request, getTree func(uuid.UUID, int, service.Viewer) (*service.FamilyTreeDto, error)) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	generations := db.MAX_GENERATIONS
+	if r.URL.Query().Has("generations") {
+		generations, err = strconv.Atoi(r.URL.Query().Get("generations"))
+		if err != nil || generations < 1 || generations > db.MAX_GENERATIONS {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(fmt.Sprintf("generations must be between 1 and %d", db.MAX_GENERATIONS)))
+			return
+		}
+	}
+
+	data, err := getTree(id, generations, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func (h *Handler) GetPersonSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit := defaultSearchLimit
+	if r.URL.Query().Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit)))
+			return
+		}
+	}
+
+	data, err := h.familyTreeService.SearchPersons(query, limit, viewerFromRequest(r))
+	if err != nil {
 		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetRelationship(w http.ResponseWriter, r *http.Request) {
+	fromId, err := uuid.Parse(r.URL.Query().Get("from"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	toId, err := uuid.Parse(r.URL.Query().Get("to"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.GetRelationship(fromId, toId, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PostPerson(w http.ResponseWriter, r *http.Request) {
+	var pr service.PostPersonRequest
+	err := json.NewDecoder(r.Body).Decode(&pr)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.CreatePerson(&pr, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.UpdatePerson(id, patch, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	err = h.familyTreeService.DeletePerson(id, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostEvent(w http.ResponseWriter, r *http.Request) {
+	personId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var er service.PostEventRequest
+	err = json.NewDecoder(r.Body).Decode(&er)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.CreateEvent(personId, &er, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
 	}
 
-	data, err := h.familyTreeService.GetFamilyTree(id, distance)
+	data, err := h.familyTreeService.UpdateEvent(id, patch, usernameFromRequest(r))
 	if err != nil {
 		errors.HandleHttpError(w, r, err)
 		return
@@ -57,6 +272,201 @@ func (h *Handler) GetFamilyTree(w http.ResponseWriter, r *http.Request) {
 	writeJson(w, data)
 }
 
+func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	err = h.familyTreeService.DeleteEvent(id, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetPersonHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.GetPersonHistory(id)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PostRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	revisionId, err := strconv.Atoi(r.PathValue("revisionId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	err = h.familyTreeService.RestoreRevision(id, revisionId, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostParentRelation(w http.ResponseWriter, r *http.Request) {
+	var relation db.ParentRelation
+	err := json.NewDecoder(r.Body).Decode(&relation)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.AddParentRelation(&relation, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteParentRelation(w http.ResponseWriter, r *http.Request) {
+	parentId, err := uuid.Parse(r.PathValue("parentId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	childId, err := uuid.Parse(r.PathValue("childId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	err = h.familyTreeService.RemoveParentRelation(parentId, childId, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostMarriageRelation(w http.ResponseWriter, r *http.Request) {
+	var relation db.MarriageRelation
+	err := json.NewDecoder(r.Body).Decode(&relation)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.AddMarriageRelation(&relation, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchMarriageRelationEnd(w http.ResponseWriter, r *http.Request) {
+	person1Id, err := uuid.Parse(r.PathValue("person1Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	person2Id, err := uuid.Parse(r.PathValue("person2Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var mer service.PatchMarriageEndRequest
+	err = json.NewDecoder(r.Body).Decode(&mer)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.EndMarriageRelation(person1Id, person2Id, &mer, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteMarriageRelation(w http.ResponseWriter, r *http.Request) {
+	person1Id, err := uuid.Parse(r.PathValue("person1Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	person2Id, err := uuid.Parse(r.PathValue("person2Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	err = h.familyTreeService.RemoveMarriageRelation(person1Id, person2Id, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostRebuildSiblingRelations(w http.ResponseWriter, r *http.Request) {
+	err := h.familyTreeService.RebuildSiblingRelations(usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const maxGedcomUploadSize = 64 * 1024 * 1024
+
+func (h *Handler) PostGedcomImport(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if r.URL.Query().Has("dryRun") {
+		var err error
+		dryRun, err = strconv.ParseBool(r.URL.Query().Get("dryRun"))
+		if err != nil {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxGedcomUploadSize)
+	data, err := h.familyTreeService.ImportGedcom(body, dryRun, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeJson(w, data)
+}
+
 func (h *Handler) GetAllFeedbacks(w http.ResponseWriter, r *http.Request) {
 	data, err := h.feedbackService.GetAllFeedbacks()
 	if err != nil {
@@ -74,7 +484,7 @@ func (h *Handler) PostFeedback(w http.ResponseWriter, r *http.Request) {
 		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
 	}
 
-	data, err := h.feedbackService.PostFeedback(fbr.Text)
+	data, err := h.feedbackService.PostFeedback(fbr.Text, usernameFromRequest(r))
 	if err != nil {
 		errors.HandleHttpError(w, r, err)
 		return
@@ -97,7 +507,7 @@ func (h *Handler) PatchFeedbackResolve(w http.ResponseWriter, r *http.Request) {
 		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
 	}
 
-	err = h.feedbackService.ResolveFeedback(id, fbr.IsResolved)
+	err = h.feedbackService.ResolveFeedback(id, fbr.IsResolved, usernameFromRequest(r))
 	if err != nil {
 		errors.HandleHttpError(w, r, err)
 		return
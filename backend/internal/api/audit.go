@@ -0,0 +1,74 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	auditDateLayout   = "2006-01-02"
+)
+
+// GetAuditEntries filters by the user who acted, the type and id of the affected entity and the time range, where
+// from and to are either RFC 3339 timestamps or dates, and to includes the whole day of a date
+func (h *Handler) GetAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.AuditFilter{
+		Username:   query.Get("user"),
+		EntityType: query.Get("entity"),
+		EntityId:   query.Get("entityId"),
+		Limit:      defaultAuditLimit,
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(query, "from", false); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+	if filter.To, err = parseAuditTime(query, "to", true); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+	if query.Has("limit") {
+		filter.Limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || filter.Limit < 1 || filter.Limit > maxAuditLimit {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit)))
+			return
+		}
+	}
+
+	data, err := h.auditService.GetAuditEntries(filter)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func parseAuditTime(query url.Values, key string, isEnd bool) (*time.Time, error) {
+	if !query.Has(key) {
+		return nil, nil
+	}
+	value := query.Get(key)
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(auditDateLayout, value, time.Local)
+	if err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("%s must be an RFC 3339 timestamp or a date", key))
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) GetAllBranches(w http.ResponseWriter, r *http.Request) {
+	data, err := h.branchService.GetAllBranches()
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PostBranch(w http.ResponseWriter, r *http.Request) {
+	var request service.PostBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.branchService.CreateBranch(request, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteBranch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.branchService.DeleteBranch(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PatchBranchPublic(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var request service.PatchBranchPublicRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.branchService.SetBranchPublic(id, request, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetPublicPersons(w http.ResponseWriter, r *http.Request) {
+	data, err := h.branchService.GetPublicPersons()
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PutPublicPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.branchService.PublishPerson(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeletePublicPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.branchService.UnpublishPerson(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetUserBranches(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.branchService.GetUserBranches(id)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PutUserBranch(w http.ResponseWriter, r *http.Request) {
+	id, branchId, err := parseUserBranchIds(r)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	if err := h.branchService.GrantBranch(id, branchId, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteUserBranch(w http.ResponseWriter, r *http.Request) {
+	id, branchId, err := parseUserBranchIds(r)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	if err := h.branchService.RevokeBranch(id, branchId, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseUserBranchIds(r *http.Request) (int, int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, 0, errors.NewBadRequestError(err.Error())
+	}
+	branchId, err := strconv.Atoi(r.PathValue("branchId"))
+	if err != nil {
+		return 0, 0, errors.NewBadRequestError(err.Error())
+	}
+	return id, branchId, nil
+}
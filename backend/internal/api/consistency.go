@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/consistency"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+)
+
+func (h *Handler) GetConsistencyReport(w http.ResponseWriter, r *http.Request) {
+	config := consistency.DefaultConfig()
+	query := r.URL.Query()
+	if query.Has("rules") {
+		config.Rules = strings.Split(query.Get("rules"), ",")
+	}
+	for name, threshold := range map[string]*int32{"minParentAge": &config.MinParentAge, "maxLifespan": &config.MaxLifespan} {
+		if !query.Has(name) {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(name), 10, 32)
+		if err != nil {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+			return
+		}
+		*threshold = int32(value)
+	}
+
+	data, err := h.familyTreeService.CheckConsistency(config)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+)
+
+const maxMediaUploadSize = 32 * 1024 * 1024
+
+func (h *Handler) PostMedia(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaUploadSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+	var caption *string
+	if r.PostForm.Has("Caption") {
+		value := r.PostForm.Get("Caption")
+		caption = &value
+	}
+
+	data, created, err := h.mediaService.UploadMedia(header.Filename, content, caption, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeJson(w, data)
+}
+
+func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.mediaService.GetMedia(id, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetMediaFile(w http.ResponseWriter, r *http.Request) {
+	h.serveMediaFile(w, r, false)
+}
+
+func (h *Handler) GetMediaThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveMediaFile(w, r, true)
+}
+
+// serveMediaFile streams the stored file, where ranges and conditional requests are handled by http.ServeContent
+func (h *Handler) serveMediaFile(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	item, file, err := h.mediaService.OpenMediaFile(id, thumbnail, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	contentType := item.ContentType
+	if thumbnail {
+		contentType = "image/jpeg"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": item.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// The content never changes, but access may be revoked, so shared caches must not keep it
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, item.FileName, stat.ModTime(), file)
+}
+
+func (h *Handler) PatchMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.mediaService.UpdateMedia(id, patch, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.mediaService.DeleteMedia(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetPersonMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.mediaService.GetPersonMedia(id, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PutPersonMedia(w http.ResponseWriter, r *http.Request) {
+	h.changeMediaAttachment(w, r, h.mediaService.AttachMediaToPerson)
+}
+
+func (h *Handler) DeletePersonMedia(w http.ResponseWriter, r *http.Request) {
+	h.changeMediaAttachment(w, r, h.mediaService.DetachMediaFromPerson)
+}
+
+func (h *Handler) GetEventMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.mediaService.GetEventMedia(id, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PutEventMedia(w http.ResponseWriter, r *http.Request) {
+	h.changeMediaAttachment(w, r, h.mediaService.AttachMediaToEvent)
+}
+
+func (h *Handler) DeleteEventMedia(w http.ResponseWriter, r *http.Request) {
+	h.changeMediaAttachment(w, r, h.mediaService.DetachMediaFromEvent)
+}
+
+// changeMediaAttachment attaches or detaches the media to or from the person or event of the path
+func (h *Handler) changeMediaAttachment(w http.ResponseWriter, r *http.Request, change func(uuid.UUID, uuid.UUID, string) error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	mediaId, err := uuid.Parse(r.PathValue("mediaId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := change(mediaId, id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) GetPlaceSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit := defaultSearchLimit
+	if r.URL.Query().Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			errors.HandleHttpError(w, r, errors.NewBadRequestError(fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit)))
+			return
+		}
+	}
+
+	data, err := h.familyTreeService.SearchPlaces(query, limit)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetPlace(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.GetPlace(id)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetPlacePersons(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.GetPlacePersons(id, viewerFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PostPlace(w http.ResponseWriter, r *http.Request) {
+	var request service.PostPlaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.CreatePlace(&request, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchPlace(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.UpdatePlace(id, patch, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeletePlace(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.familyTreeService.DeletePlace(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostPlaceMerge(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	duplicateId, err := uuid.Parse(r.PathValue("duplicateId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.MergePlace(id, duplicateId, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
@@ -3,19 +3,29 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
 	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 )
 
+const REFRESH_TOKEN_COOKIE = "family_tree-refresh_token"
+
+// The refresh token is sent to the whole security API, as both refreshing and logging out need it
+const REFRESH_TOKEN_COOKIE_PATH = "/api/security"
+const LEGACY_REFRESH_TOKEN_COOKIE_PATH = "/api/security/token"
+
 type SecurityHandler struct {
 	securityService *service.SecurityService
+	trustedProxy    bool
 }
 
-func NewSecurityHandler(sqlDb *sql.DB) *SecurityHandler {
+func NewSecurityHandler(sqlDb *sql.DB, trustedProxy bool) *SecurityHandler {
 	return &SecurityHandler{
-		securityService: service.NewSecurityService(sqlDb),
+		securityService: service.NewSecurityService(sqlDb, service.NewAuditLog(sqlDb)),
+		trustedProxy:    trustedProxy,
 	}
 }
 
@@ -27,28 +37,20 @@ func (h *SecurityHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rt, at, err := h.securityService.Login(login.Username, login.Password)
+	rt, at, err := h.securityService.Login(login.Username, login.Password, h.clientIp(r))
 	if err != nil {
 		errors.HandleHttpError(w, r, err)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "family_tree-refresh_token",
-		Value:    rt,
-		HttpOnly: true,
-		Secure:   false,
-		Path:     "/api/security/token",
-		MaxAge:   30 * 24 * 60 * 60, // 7 days
-		SameSite: http.SameSiteStrictMode,
-	})
+	setRefreshTokenCookie(w, rt)
 
 	dto := service.AccessTokenDto{AccessToken: at}
 	writeJson(w, dto)
 }
 
 func (h *SecurityHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("family_tree-refresh_token")
+	cookie, err := r.Cookie(REFRESH_TOKEN_COOKIE)
 	if err != nil {
 		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
 		return
@@ -61,16 +63,103 @@ func (h *SecurityHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setRefreshTokenCookie(w, rt)
+
+	dto := service.AccessTokenDto{AccessToken: at}
+	writeJson(w, dto)
+}
+
+func (h *SecurityHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(REFRESH_TOKEN_COOKIE); err == nil {
+		if err := h.securityService.Logout(cookie.Value); err != nil {
+			errors.HandleHttpError(w, r, err)
+			return
+		}
+	}
+
+	clearRefreshTokenCookie(w, REFRESH_TOKEN_COOKIE_PATH)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SecurityHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var request service.ChangePasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	rt, at, err := h.securityService.ChangePassword(usernameFromRequest(r), request.OldPassword, request.NewPassword)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	setRefreshTokenCookie(w, rt)
+
+	dto := service.AccessTokenDto{AccessToken: at}
+	writeJson(w, dto)
+}
+
+func (h *SecurityHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var request service.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	if err := h.securityService.ResetPassword(request.Token, request.NewPassword); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setRefreshTokenCookie(w http.ResponseWriter, refreshToken string) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "family_tree-refresh_token",
-		Value:    rt,
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    refreshToken,
 		HttpOnly: true,
 		Secure:   false, // bad for local development
-		Path:     "/api/security/token",
+		Path:     REFRESH_TOKEN_COOKIE_PATH,
 		MaxAge:   30 * 24 * 60 * 60, // 30 days
 		SameSite: http.SameSiteStrictMode,
 	})
+	// The cookie used to be scoped to the refresh endpoint, where it would shadow the current one
+	clearRefreshTokenCookie(w, LEGACY_REFRESH_TOKEN_COOKIE_PATH)
+}
 
-	dto := service.AccessTokenDto{AccessToken: at}
-	writeJson(w, dto)
+func clearRefreshTokenCookie(w http.ResponseWriter, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    "",
+		HttpOnly: true,
+		Secure:   false,
+		Path:     path,
+		MaxAge:   -1,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clientIp returns the address the lockout of failed logins is keyed by. Behind a trusted reverse proxy, the remote
+// address is the one of the proxy, so the last entry of X-Forwarded-For is used instead, which is the one the proxy
+// appended itself. All entries before it are sent by the client and could be forged.
+func (h *SecurityHandler) clientIp(r *http.Request) string {
+	if h.trustedProxy {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); len(ip) > 0 {
+				return ip
+			}
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
 }
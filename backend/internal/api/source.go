@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) GetAllSources(w http.ResponseWriter, r *http.Request) {
+	data, err := h.familyTreeService.GetAllSources()
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) GetSource(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.GetSource(id)
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) PostSource(w http.ResponseWriter, r *http.Request) {
+	var request service.PostSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.CreateSource(&request, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchSource(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.UpdateSource(id, patch, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteSource(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.familyTreeService.DeleteSource(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PostPersonCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	h.postCitation(w, r, func(request *service.PostCitationRequest, username string) (any, error) {
+		return h.familyTreeService.CreatePersonCitation(id, request, username)
+	})
+}
+
+func (h *Handler) PostEventCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	h.postCitation(w, r, func(request *service.PostCitationRequest, username string) (any, error) {
+		return h.familyTreeService.CreateEventCitation(id, request, username)
+	})
+}
+
+func (h *Handler) PostParentRelationCitation(w http.ResponseWriter, r *http.Request) {
+	parentId, err := uuid.Parse(r.PathValue("parentId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	childId, err := uuid.Parse(r.PathValue("childId"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	h.postCitation(w, r, func(request *service.PostCitationRequest, username string) (any, error) {
+		return h.familyTreeService.CreateParentRelationCitation(parentId, childId, request, username)
+	})
+}
+
+func (h *Handler) PostMarriageRelationCitation(w http.ResponseWriter, r *http.Request) {
+	person1Id, err := uuid.Parse(r.PathValue("person1Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	person2Id, err := uuid.Parse(r.PathValue("person2Id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	h.postCitation(w, r, func(request *service.PostCitationRequest, username string) (any, error) {
+		return h.familyTreeService.CreateMarriageCitation(person1Id, person2Id, request, username)
+	})
+}
+
+// postCitation decodes the citation for any kind of subject, as only the creation itself differs
+func (h *Handler) postCitation(w http.ResponseWriter, r *http.Request, create func(*service.PostCitationRequest, string) (any, error)) {
+	var request service.PostCitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := create(&request, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJson(w, data)
+}
+
+func (h *Handler) PatchCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		errors.HandleHttpError(w, r, errors.NewUnprocessableEntityError(err.Error()))
+		return
+	}
+
+	data, err := h.familyTreeService.UpdateCitation(id, patch, usernameFromRequest(r))
+	if err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	writeJson(w, data)
+}
+
+func (h *Handler) DeleteCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errors.HandleHttpError(w, r, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.familyTreeService.DeleteCitation(id, usernameFromRequest(r)); err != nil {
+		errors.HandleHttpError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,7 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/constants"
-	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 )
 
 func writeJson(w http.ResponseWriter, data any) {
@@ -20,26 +20,15 @@ func writeJson(w http.ResponseWriter, data any) {
 	}
 }
 
-var dummyData = map[string]bool{
-	"01994d49-826f-76ac-aead-5bdf618ef2c5": true,
-	"01994d49-8270-755a-bbb1-310ed0140db2": true,
-	"01994d49-8270-755a-bbb1-37d4fda28301": true,
-	"01994d49-8270-755a-bbb1-390cb53d432b": true,
-	"01994d49-8270-755a-bbb1-3d7704871402": true,
-	"01994d49-8270-755a-bbb1-42bf3f1fd9ba": true,
-	"01994d49-8270-755a-bbb1-4eac575bec0f": true,
-	"01994d49-8270-755a-bbb1-50baa8e31af1": true,
-	"01994d49-8270-755a-bbb1-5435c2658136": true,
-	"01994d49-8270-755a-bbb1-5a0ee242a486": true,
-	"01994d49-8270-755a-bbb1-5cb5aaa32746": true,
-	"01994d49-8270-755a-bbb1-616f585516a3": true,
-	"01994d49-8270-755a-bbb1-65adc88896bb": true,
-	"01994d49-8270-755a-bbb1-68a322cd4491": true,
+func viewerFromRequest(r *http.Request) service.Viewer {
+	userId, _ := r.Context().Value(constants.AUTH_CONTEXT_USER_ID).(int)
+	role, _ := r.Context().Value(constants.AUTH_CONTEXT_ROLE).(string)
+	nodeId, _ := r.Context().Value(constants.AUTH_CONTEXT_NODE).(string)
+	return service.Viewer{UserId: userId, Role: role, NodeId: nodeId}
 }
 
-func allowDummyDataForUnauthorized(r *http.Request, id string) error {
-	if r.Context().Value(constants.AUTH_CONTEXT_ROLE) != nil || dummyData[id] {
-		return nil
-	}
-	return errors.NewForbiddenError("Insufficient privileges")
+// usernameFromRequest is empty for anonymous requests
+func usernameFromRequest(r *http.Request) string {
+	username, _ := r.Context().Value(constants.AUTH_CONTEXT_USERNAME).(string)
+	return username
 }
@@ -4,22 +4,29 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/db"
 	"github.com/Sakrafux/family-tree-app/backend/internal/middleware"
 	"github.com/Sakrafux/family-tree-app/backend/internal/router"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 	"github.com/kuzudb/go-kuzu"
 )
 
 type AppConfig struct {
-	DB_KUZU_PATH   string
-	DB_SQLITE_PATH string
-	PORT           string
+	DB_KUZU_PATH      string
+	DB_SQLITE_PATH    string
+	MEDIA_PATH        string
+	PORT              string
+	KUZU_POOL_SIZE    int
+	KUZU_POOL_TIMEOUT time.Duration
+	ACCESS            service.AccessConfig
+	TRUSTED_PROXY     bool
 }
 
 type DbContext struct {
 	kuzuDb   *kuzu.Database
-	kuzuConn *kuzu.Connection
+	kuzuPool *db.KuzuPool
 	sqlDB    *sql.DB
 }
 
@@ -30,7 +37,7 @@ type App struct {
 }
 
 func NewApp(config *AppConfig) *App {
-	return &App{config: config, db: &DbContext{kuzuDb: nil, kuzuConn: nil}}
+	return &App{config: config, db: &DbContext{kuzuDb: nil, kuzuPool: nil}}
 }
 
 func (app *App) Start() {
@@ -47,12 +54,12 @@ func (app *App) Start() {
 }
 
 func (app *App) connectToDatabases(kuzuPath, sqlitePath string) {
-	app.db.kuzuDb, app.db.kuzuConn = db.ConnectToKuzu(kuzuPath)
+	app.db.kuzuDb, app.db.kuzuPool = db.ConnectToKuzu(kuzuPath, app.config.KUZU_POOL_SIZE, app.config.KUZU_POOL_TIMEOUT)
 	app.db.sqlDB = db.ConnectToSqlite(sqlitePath)
 }
 
 func (app *App) closeDatabase() {
-	app.db.kuzuConn.Close()
+	app.db.kuzuPool.Close()
 	app.db.kuzuDb.Close()
 	app.db.sqlDB.Close()
 }
@@ -64,5 +71,5 @@ func (app *App) createRouter() http.Handler {
 		middleware.Authentication(app.db.sqlDB),
 	)
 
-	return stack(router.CreaterRouter(app.db.kuzuConn, app.db.sqlDB))
+	return stack(router.CreaterRouter(app.db.kuzuPool, app.db.sqlDB, app.config.ACCESS, app.config.MEDIA_PATH, app.config.TRUSTED_PROXY))
 }
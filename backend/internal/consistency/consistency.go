@@ -0,0 +1,368 @@
+// Package consistency finds impossible or implausible data in the family graph, e.g. a child born before its parent
+// or a cycle of parent relations. Uncertain dates are only reported if no possible day satisfies the rule.
+package consistency
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+)
+
+const (
+	RULE_DEATH_BEFORE_BIRTH   = "death-before-birth"
+	RULE_CHILD_BEFORE_PARENT  = "child-born-before-parent"
+	RULE_PARENT_TOO_YOUNG     = "parent-too-young"
+	RULE_TOO_MANY_PARENTS     = "too-many-parents"
+	RULE_MARRIAGE_AFTER_DEATH = "marriage-after-death"
+	RULE_PARENT_CYCLE         = "parent-cycle"
+	RULE_IMPLAUSIBLE_LIFESPAN = "implausible-lifespan"
+)
+
+const (
+	DEFAULT_MIN_PARENT_AGE = 12
+	DEFAULT_MAX_LIFESPAN   = 120
+)
+
+// Graph is the part of the family graph the rules are checked against
+type Graph struct {
+	Persons           []*db.Person
+	ParentRelations   []*db.ParentRelation
+	MarriageRelations []*db.MarriageRelation
+}
+
+// Config selects the rules to check and configures their thresholds
+type Config struct {
+	// Rules are the names of the rules to check, where an empty list checks all rules
+	Rules []string
+	// MinParentAge is the age a parent must have reached at the birth of the child
+	MinParentAge int32
+	// MaxLifespan is the age above which a lifespan is reported as implausible
+	MaxLifespan int32
+}
+
+func DefaultConfig() Config {
+	return Config{MinParentAge: DEFAULT_MIN_PARENT_AGE, MaxLifespan: DEFAULT_MAX_LIFESPAN}
+}
+
+type Issue struct {
+	Rule     string
+	Severity string
+	// PersonIds are the persons involved, e.g. the parent and the child
+	PersonIds []uuid.UUID
+	Message   string
+}
+
+type Report struct {
+	Rules    []string
+	Errors   int
+	Warnings int
+	Issues   []*Issue
+}
+
+type rule struct {
+	name     string
+	severity string
+	check    func(g *graphIndex, config Config) []*Issue
+}
+
+// rules are checked in this order, which is also the order of the issues in the report
+var rules = []rule{
+	{RULE_PARENT_CYCLE, SEVERITY_ERROR, checkParentCycles},
+	{RULE_TOO_MANY_PARENTS, SEVERITY_ERROR, checkTooManyParents},
+	{RULE_DEATH_BEFORE_BIRTH, SEVERITY_ERROR, checkDeathBeforeBirth},
+	{RULE_CHILD_BEFORE_PARENT, SEVERITY_ERROR, checkChildBeforeParent},
+	{RULE_PARENT_TOO_YOUNG, SEVERITY_ERROR, checkParentTooYoung},
+	{RULE_MARRIAGE_AFTER_DEATH, SEVERITY_ERROR, checkMarriageAfterDeath},
+	{RULE_IMPLAUSIBLE_LIFESPAN, SEVERITY_WARNING, checkImplausibleLifespan},
+}
+
+// RuleNames returns the names of all rules in the order they are checked
+func RuleNames() []string {
+	names := make([]string, 0, len(rules))
+	for _, r := range rules {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// Check runs the configured rules against the graph and fails only for unknown rule names
+func Check(graph *Graph, config Config) (*Report, error) {
+	selected := make(map[string]bool)
+	for _, name := range config.Rules {
+		if !slices.ContainsFunc(rules, func(r rule) bool { return r.name == name }) {
+			return nil, fmt.Errorf("unknown rule '%s'", name)
+		}
+		selected[name] = true
+	}
+
+	g := newGraphIndex(graph)
+	report := &Report{Rules: make([]string, 0), Issues: make([]*Issue, 0)}
+	for _, r := range rules {
+		if len(selected) > 0 && !selected[r.name] {
+			continue
+		}
+		report.Rules = append(report.Rules, r.name)
+
+		for _, issue := range r.check(g, config) {
+			issue.Rule, issue.Severity = r.name, r.severity
+			report.Issues = append(report.Issues, issue)
+			if r.severity == SEVERITY_ERROR {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// graphIndex gives the rules quick access to persons and their relations, where persons are sorted by id so that
+// reports are deterministic
+type graphIndex struct {
+	persons   []*db.Person
+	byId      map[uuid.UUID]*db.Person
+	parents   map[uuid.UUID][]uuid.UUID
+	children  map[uuid.UUID][]uuid.UUID
+	marriages []*db.MarriageRelation
+}
+
+func newGraphIndex(graph *Graph) *graphIndex {
+	g := &graphIndex{
+		persons:   slices.Clone(graph.Persons),
+		byId:      make(map[uuid.UUID]*db.Person, len(graph.Persons)),
+		parents:   make(map[uuid.UUID][]uuid.UUID),
+		children:  make(map[uuid.UUID][]uuid.UUID),
+		marriages: slices.Clone(graph.MarriageRelations),
+	}
+	slices.SortFunc(g.persons, func(a, b *db.Person) int {
+		return strings.Compare(a.Id.String(), b.Id.String())
+	})
+	slices.SortFunc(g.marriages, func(a, b *db.MarriageRelation) int {
+		return strings.Compare(a.Person1Id.String()+a.Person2Id.String(), b.Person1Id.String()+b.Person2Id.String())
+	})
+	for _, person := range g.persons {
+		g.byId[person.Id] = person
+	}
+	for _, relation := range graph.ParentRelations {
+		g.parents[relation.ChildId] = append(g.parents[relation.ChildId], relation.ParentId)
+		g.children[relation.ParentId] = append(g.children[relation.ParentId], relation.ChildId)
+	}
+	for _, ids := range g.children {
+		slices.SortFunc(ids, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	}
+	return g
+}
+
+// forEachParentRelation calls fn for every parent relation between known persons, ordered by parent and child
+func (g *graphIndex) forEachParentRelation(fn func(parent, child *db.Person)) {
+	for _, parent := range g.persons {
+		for _, childId := range g.children[parent.Id] {
+			if child, ok := g.byId[childId]; ok {
+				fn(parent, child)
+			}
+		}
+	}
+}
+
+func checkParentCycles(g *graphIndex, _ Config) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, component := range stronglyConnectedComponents(g) {
+		isSelfParent := len(component) == 1 && slices.Contains(g.children[component[0]], component[0])
+		if len(component) == 1 && !isSelfParent {
+			continue
+		}
+
+		names := make([]string, 0, len(component))
+		for _, id := range component {
+			names = append(names, personName(g.byId[id]))
+		}
+		issues = append(issues, &Issue{
+			PersonIds: component,
+			Message:   fmt.Sprintf("parent relations form a cycle through %s", strings.Join(names, ", ")),
+		})
+	}
+	return issues
+}
+
+// stronglyConnectedComponents finds the groups of persons which are all ancestors of each other, see
+// https://en.wikipedia.org/wiki/Tarjan%27s_strongly_connected_components_algorithm
+func stronglyConnectedComponents(g *graphIndex) [][]uuid.UUID {
+	index := make(map[uuid.UUID]int)
+	lowLink := make(map[uuid.UUID]int)
+	onStack := make(map[uuid.UUID]bool)
+	stack := make([]uuid.UUID, 0)
+	components := make([][]uuid.UUID, 0)
+
+	var visit func(id uuid.UUID)
+	visit = func(id uuid.UUID) {
+		index[id], lowLink[id] = len(index), len(index)
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, childId := range g.children[id] {
+			if _, ok := index[childId]; !ok {
+				visit(childId)
+				lowLink[id] = min(lowLink[id], lowLink[childId])
+			} else if onStack[childId] {
+				lowLink[id] = min(lowLink[id], index[childId])
+			}
+		}
+
+		if lowLink[id] == index[id] {
+			component := make([]uuid.UUID, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			slices.Reverse(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, person := range g.persons {
+		if _, ok := index[person.Id]; !ok {
+			visit(person.Id)
+		}
+	}
+	return components
+}
+
+func checkTooManyParents(g *graphIndex, _ Config) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, person := range g.persons {
+		parentIds := g.parents[person.Id]
+		if len(parentIds) <= 2 {
+			continue
+		}
+		issues = append(issues, &Issue{
+			PersonIds: append([]uuid.UUID{person.Id}, parentIds...),
+			Message:   fmt.Sprintf("%s has %d parents", personName(person), len(parentIds)),
+		})
+	}
+	return issues
+}
+
+func checkDeathBeforeBirth(g *graphIndex, _ Config) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, person := range g.persons {
+		birthEarliest, _ := person.BirthDate().Bounds()
+		_, deathLatest := person.DeathDate().Bounds()
+		if birthEarliest == nil || deathLatest == nil || !deathLatest.Before(*birthEarliest) {
+			continue
+		}
+		issues = append(issues, &Issue{
+			PersonIds: []uuid.UUID{person.Id},
+			Message:   fmt.Sprintf("%s died before being born", personName(person)),
+		})
+	}
+	return issues
+}
+
+func checkChildBeforeParent(g *graphIndex, _ Config) []*Issue {
+	issues := make([]*Issue, 0)
+	g.forEachParentRelation(func(parent, child *db.Person) {
+		parentEarliest, _ := parent.BirthDate().Bounds()
+		_, childLatest := child.BirthDate().Bounds()
+		if parentEarliest == nil || childLatest == nil || !childLatest.Before(*parentEarliest) {
+			return
+		}
+		issues = append(issues, &Issue{
+			PersonIds: []uuid.UUID{parent.Id, child.Id},
+			Message:   fmt.Sprintf("%s was born before the parent %s", personName(child), personName(parent)),
+		})
+	})
+	return issues
+}
+
+func checkParentTooYoung(g *graphIndex, config Config) []*Issue {
+	issues := make([]*Issue, 0)
+	g.forEachParentRelation(func(parent, child *db.Person) {
+		parentEarliest, _ := parent.BirthDate().Bounds()
+		_, childLatest := child.BirthDate().Bounds()
+		if parentEarliest == nil || childLatest == nil {
+			return
+		}
+		// Children born before their parent are reported by RULE_CHILD_BEFORE_PARENT
+		maxAge := dates.YearsBetween(*parentEarliest, *childLatest)
+		if childLatest.Before(*parentEarliest) || maxAge >= config.MinParentAge {
+			return
+		}
+		issues = append(issues, &Issue{
+			PersonIds: []uuid.UUID{parent.Id, child.Id},
+			Message: fmt.Sprintf("%s was at most %d years old at the birth of %s",
+				personName(parent), maxAge, personName(child)),
+		})
+	})
+	return issues
+}
+
+func checkMarriageAfterDeath(g *graphIndex, _ Config) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, marriage := range g.marriages {
+		since := dates.Date{Year: marriage.SinceYear, Month: marriage.SinceMonth, Day: marriage.SinceDay}
+		sinceEarliest, _ := since.Bounds()
+		if sinceEarliest == nil {
+			continue
+		}
+
+		for _, id := range []uuid.UUID{marriage.Person1Id, marriage.Person2Id} {
+			person, ok := g.byId[id]
+			if !ok {
+				continue
+			}
+			_, deathLatest := person.DeathDate().Bounds()
+			if deathLatest == nil || !deathLatest.Before(*sinceEarliest) {
+				continue
+			}
+			issues = append(issues, &Issue{
+				PersonIds: []uuid.UUID{marriage.Person1Id, marriage.Person2Id},
+				Message:   fmt.Sprintf("%s married in %d after having died", personName(person), *marriage.SinceYear),
+			})
+		}
+	}
+	return issues
+}
+
+func checkImplausibleLifespan(g *graphIndex, config Config) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, person := range g.persons {
+		minAge, _ := dates.AgeRange(person.BirthDate(), person.DeathDate())
+		if minAge == nil || *minAge <= config.MaxLifespan {
+			continue
+		}
+		issues = append(issues, &Issue{
+			PersonIds: []uuid.UUID{person.Id},
+			Message:   fmt.Sprintf("%s lived at least %d years", personName(person), *minAge),
+		})
+	}
+	return issues
+}
+
+// personName quotes the full name, falling back to the id for persons without names
+func personName(person *db.Person) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []*string{person.FirstName, person.MiddleName, person.LastName} {
+		if part != nil {
+			parts = append(parts, *part)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("'%s'", person.Id)
+	}
+	return fmt.Sprintf("'%s'", strings.Join(parts, " "))
+}
@@ -0,0 +1,193 @@
+package consistency
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+func id(n int) uuid.UUID {
+	return uuid.MustParse(fmt.Sprintf("00000000-0000-7000-8000-%012d", n))
+}
+
+func year(y int32) *int32 {
+	return &y
+}
+
+// person creates a person whose birth and death are only known by year, where 0 is unknown
+func person(n int, birthYear, deathYear int32) *db.Person {
+	p := &db.Person{Id: id(n)}
+	if birthYear != 0 {
+		p.BirthDateYear = year(birthYear)
+	}
+	if deathYear != 0 {
+		p.DeathDateYear = year(deathYear)
+	}
+	return p
+}
+
+func about(p *db.Person) *db.Person {
+	qualifier := dates.QUALIFIER_ABOUT
+	p.BirthDateQualifier = &qualifier
+	return p
+}
+
+func parentOf(parent, child int) *db.ParentRelation {
+	return &db.ParentRelation{ParentId: id(parent), ChildId: id(child)}
+}
+
+func marriage(person1, person2 int, sinceYear int32) *db.MarriageRelation {
+	return &db.MarriageRelation{Person1Id: id(person1), Person2Id: id(person2), SinceYear: year(sinceYear)}
+}
+
+type wantIssue struct {
+	rule      string
+	personIds []uuid.UUID
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		graph  Graph
+		config Config
+		want   []wantIssue
+	}{
+		{"consistent family", Graph{
+			Persons:           []*db.Person{person(1, 1900, 1970), person(2, 1902, 1980), person(3, 1930, 0)},
+			ParentRelations:   []*db.ParentRelation{parentOf(1, 3), parentOf(2, 3)},
+			MarriageRelations: []*db.MarriageRelation{marriage(1, 2, 1925)},
+		}, DefaultConfig(), nil},
+		{"unknown dates", Graph{
+			Persons:         []*db.Person{person(1, 0, 0), person(2, 0, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, DefaultConfig(), nil},
+		{"parent cycle", Graph{
+			Persons:         []*db.Person{person(1, 0, 0), person(2, 0, 0), person(3, 0, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2), parentOf(2, 3), parentOf(3, 1)},
+		}, DefaultConfig(), []wantIssue{{RULE_PARENT_CYCLE, []uuid.UUID{id(1), id(2), id(3)}}}},
+		{"own parent", Graph{
+			Persons:         []*db.Person{person(1, 0, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 1)},
+		}, DefaultConfig(), []wantIssue{{RULE_PARENT_CYCLE, []uuid.UUID{id(1)}}}},
+		{"too many parents", Graph{
+			Persons:         []*db.Person{person(1, 0, 0), person(2, 0, 0), person(3, 0, 0), person(4, 0, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 4), parentOf(2, 4), parentOf(3, 4)},
+		}, DefaultConfig(), []wantIssue{{RULE_TOO_MANY_PARENTS, []uuid.UUID{id(4), id(1), id(2), id(3)}}}},
+		{"death before birth", Graph{
+			Persons: []*db.Person{person(1, 1900, 1899)},
+		}, DefaultConfig(), []wantIssue{{RULE_DEATH_BEFORE_BIRTH, []uuid.UUID{id(1)}}}},
+		{"death possibly after approximate birth", Graph{
+			Persons: []*db.Person{about(person(1, 1900, 1899))},
+		}, DefaultConfig(), nil},
+		{"child born before parent", Graph{
+			Persons:         []*db.Person{person(1, 1900, 0), person(2, 1890, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, DefaultConfig(), []wantIssue{{RULE_CHILD_BEFORE_PARENT, []uuid.UUID{id(1), id(2)}}}},
+		{"parent too young", Graph{
+			Persons:         []*db.Person{person(1, 1900, 0), person(2, 1911, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, DefaultConfig(), []wantIssue{{RULE_PARENT_TOO_YOUNG, []uuid.UUID{id(1), id(2)}}}},
+		{"parent possibly old enough", Graph{
+			Persons:         []*db.Person{person(1, 1900, 0), person(2, 1912, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, DefaultConfig(), nil},
+		{"lower minimum parent age", Graph{
+			Persons:         []*db.Person{person(1, 1900, 0), person(2, 1905, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, Config{MinParentAge: 5, MaxLifespan: DEFAULT_MAX_LIFESPAN}, nil},
+		{"marriage after death", Graph{
+			Persons:           []*db.Person{person(1, 1850, 1900), person(2, 1860, 0)},
+			MarriageRelations: []*db.MarriageRelation{marriage(1, 2, 1901)},
+		}, DefaultConfig(), []wantIssue{{RULE_MARRIAGE_AFTER_DEATH, []uuid.UUID{id(1), id(2)}}}},
+		{"marriage in year of death", Graph{
+			Persons:           []*db.Person{person(1, 1850, 1900), person(2, 1860, 0)},
+			MarriageRelations: []*db.MarriageRelation{marriage(1, 2, 1900)},
+		}, DefaultConfig(), nil},
+		{"implausible lifespan", Graph{
+			Persons: []*db.Person{person(1, 1800, 1925)},
+		}, DefaultConfig(), []wantIssue{{RULE_IMPLAUSIBLE_LIFESPAN, []uuid.UUID{id(1)}}}},
+		{"higher maximum lifespan", Graph{
+			Persons: []*db.Person{person(1, 1800, 1925)},
+		}, Config{MinParentAge: DEFAULT_MIN_PARENT_AGE, MaxLifespan: 130}, nil},
+		{"selected rules only", Graph{
+			Persons:         []*db.Person{person(1, 1900, 1899), person(2, 1905, 0)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, Config{Rules: []string{RULE_DEATH_BEFORE_BIRTH}}, []wantIssue{{RULE_DEATH_BEFORE_BIRTH, []uuid.UUID{id(1)}}}},
+		{"issues in rule order", Graph{
+			Persons:         []*db.Person{person(1, 1800, 1925), person(2, 1900, 1899)},
+			ParentRelations: []*db.ParentRelation{parentOf(1, 2)},
+		}, DefaultConfig(), []wantIssue{
+			{RULE_DEATH_BEFORE_BIRTH, []uuid.UUID{id(2)}},
+			{RULE_IMPLAUSIBLE_LIFESPAN, []uuid.UUID{id(1)}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Check(&tt.graph, tt.config)
+			if err != nil {
+				t.Fatalf("Check() failed: %v", err)
+			}
+
+			got := make([]wantIssue, 0, len(report.Issues))
+			for _, issue := range report.Issues {
+				got = append(got, wantIssue{issue.Rule, issue.PersonIds})
+			}
+			if !slices.EqualFunc(got, tt.want, func(a, b wantIssue) bool {
+				return a.rule == b.rule && slices.Equal(a.personIds, b.personIds)
+			}) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+
+			errors, warnings := 0, 0
+			for _, issue := range tt.want {
+				if issue.rule == RULE_IMPLAUSIBLE_LIFESPAN {
+					warnings++
+				} else {
+					errors++
+				}
+			}
+			if report.Errors != errors || report.Warnings != warnings {
+				t.Errorf("errors, warnings = %d, %d, want %d, %d", report.Errors, report.Warnings, errors, warnings)
+			}
+		})
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		want    []string
+		wantErr bool
+	}{
+		{"all rules by default", nil, RuleNames(), false},
+		{"selection in check order", []string{RULE_IMPLAUSIBLE_LIFESPAN, RULE_PARENT_CYCLE},
+			[]string{RULE_PARENT_CYCLE, RULE_IMPLAUSIBLE_LIFESPAN}, false},
+		{"unknown rule", []string{RULE_PARENT_CYCLE, "unknown"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Rules = tt.rules
+			report, err := Check(&Graph{}, config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Check() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() failed: %v", err)
+			}
+			if !slices.Equal(report.Rules, tt.want) {
+				t.Errorf("rules = %v, want %v", report.Rules, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ const (
 	AUTH_PERMISSION_READ  = "READ"
 	AUTH_PERMISSION_ADMIN = "ADMIN"
 
+	AUTH_CONTEXT_USER_ID     = "userId"
 	AUTH_CONTEXT_USERNAME    = "username"
 	AUTH_CONTEXT_ROLE        = "role"
 	AUTH_CONTEXT_PERMISSIONS = "permissions"
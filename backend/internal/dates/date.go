@@ -0,0 +1,186 @@
+// Package dates models genealogical dates, which are often partial ("1820"), approximate ("about 1820"), open-ended
+// ("before 1750") or ranges ("between 1800 and 1810"), and possibly recorded in the Julian calendar. Such a date is
+// not a point in time but an interval of possible days, which is what comparisons and ages are derived from.
+package dates
+
+import (
+	"cmp"
+	"time"
+)
+
+const (
+	QUALIFIER_ABOUT   = "about"
+	QUALIFIER_BEFORE  = "before"
+	QUALIFIER_AFTER   = "after"
+	QUALIFIER_BETWEEN = "between"
+)
+
+const (
+	CALENDAR_GREGORIAN = "gregorian"
+	CALENDAR_JULIAN    = "julian"
+)
+
+// APPROXIMATE_YEARS is how many years an approximate date may be off in either direction
+const APPROXIMATE_YEARS = 5
+
+// julianDayOf2000 is the Julian day number of 2000-01-01, which anchors the conversion from the Julian calendar
+const julianDayOf2000 = 2451545
+
+// Date is a partial date with an optional qualifier, where a missing year makes the whole date unknown
+type Date struct {
+	Year  *int32
+	Month *int32
+	Day   *int32
+	// Qualifier is nil for an exact date
+	Qualifier *string
+	// ToYear, ToMonth and ToDay are the end of the range of QUALIFIER_BETWEEN
+	ToYear  *int32
+	ToMonth *int32
+	ToDay   *int32
+	// Calendar is nil for the Gregorian calendar
+	Calendar *string
+}
+
+func (d Date) IsKnown() bool {
+	return d.Year != nil
+}
+
+// IsExact reports whether the date is a plain Gregorian date, which may still lack the month or day
+func (d Date) IsExact() bool {
+	return d.IsKnown() && d.Qualifier == nil && (d.Calendar == nil || *d.Calendar == CALENDAR_GREGORIAN)
+}
+
+// Bounds returns the earliest and the latest possible day in the Gregorian calendar, where nil means unbounded.
+// Both are nil if the date is unknown.
+func (d Date) Bounds() (earliest, latest *time.Time) {
+	if !d.IsKnown() {
+		return nil, nil
+	}
+
+	first, last := span(d.Calendar, *d.Year, d.Month, d.Day)
+	qualifier := ""
+	if d.Qualifier != nil {
+		qualifier = *d.Qualifier
+	}
+
+	switch qualifier {
+	case QUALIFIER_ABOUT:
+		first, last = first.AddDate(-APPROXIMATE_YEARS, 0, 0), last.AddDate(APPROXIMATE_YEARS, 0, 0)
+	case QUALIFIER_BEFORE:
+		last = first.AddDate(0, 0, -1)
+		return nil, &last
+	case QUALIFIER_AFTER:
+		first = last.AddDate(0, 0, 1)
+		return &first, nil
+	case QUALIFIER_BETWEEN:
+		if d.ToYear != nil {
+			_, last = span(d.Calendar, *d.ToYear, d.ToMonth, d.ToDay)
+		}
+	}
+	return &first, &last
+}
+
+// Estimate returns the most likely day, which is the middle of the bounds or the bound of an open-ended date
+func (d Date) Estimate() (time.Time, bool) {
+	earliest, latest := d.Bounds()
+	switch {
+	case earliest != nil && latest != nil:
+		return time.Unix((earliest.Unix()+latest.Unix())/2, 0).UTC(), true
+	case earliest != nil:
+		return *earliest, true
+	case latest != nil:
+		return *latest, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Compare orders dates by their estimate, where unknown dates come last
+func Compare(a, b Date) int {
+	ae, aok := a.Estimate()
+	be, bok := b.Estimate()
+	if !aok || !bok {
+		// true > false, so known dates come first
+		return cmp.Compare(boolToInt(!aok), boolToInt(!bok))
+	}
+	return ae.Compare(be)
+}
+
+// AgeRange returns the least and the greatest number of full years possibly between the dates, where nil means
+// unbounded or unknown
+func AgeRange(from, to Date) (minAge, maxAge *int32) {
+	fromEarliest, fromLatest := from.Bounds()
+	toEarliest, toLatest := to.Bounds()
+
+	if fromLatest != nil && toEarliest != nil {
+		age := max(YearsBetween(*fromLatest, *toEarliest), 0)
+		minAge = &age
+	}
+	if fromEarliest != nil && toLatest != nil {
+		age := max(YearsBetween(*fromEarliest, *toLatest), 0)
+		maxAge = &age
+	}
+	return minAge, maxAge
+}
+
+// YearsBetween returns the number of full years from one day to another, which is negative if to is before from
+func YearsBetween(from, to time.Time) int32 {
+	years := int32(to.Year() - from.Year())
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return years
+}
+
+// Today returns the current day as an exact date
+func Today() Date {
+	now := time.Now()
+	year, month, day := int32(now.Year()), int32(now.Month()), int32(now.Day())
+	return Date{Year: &year, Month: &month, Day: &day}
+}
+
+// DaysInMonth returns the number of days of the month in the calendar, which defaults to the Gregorian calendar
+func DaysInMonth(calendar *string, year, month int32) int32 {
+	if calendar != nil && *calendar == CALENDAR_JULIAN && month == 2 {
+		// The Julian calendar has a leap year every four years, also in 1700, 1800 and 1900
+		if year%4 == 0 {
+			return 29
+		}
+		return 28
+	}
+	return int32(time.Date(int(year), time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day())
+}
+
+// span returns the first and the last day of a partial date as Gregorian days
+func span(calendar *string, year int32, month, day *int32) (first, last time.Time) {
+	firstMonth, lastMonth := int32(1), int32(12)
+	if month != nil {
+		firstMonth, lastMonth = *month, *month
+	}
+	firstDay, lastDay := int32(1), DaysInMonth(calendar, year, lastMonth)
+	if month != nil && day != nil {
+		firstDay, lastDay = *day, *day
+	}
+	return toGregorian(calendar, year, firstMonth, firstDay), toGregorian(calendar, year, lastMonth, lastDay)
+}
+
+func toGregorian(calendar *string, year, month, day int32) time.Time {
+	if calendar == nil || *calendar != CALENDAR_JULIAN {
+		return time.Date(int(year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC)
+	}
+
+	// Julian day number of the Julian calendar date, see https://en.wikipedia.org/wiki/Julian_day
+	a := (14 - int(month)) / 12
+	y := int(year) + 4800 - a
+	m := int(month) + 12*a - 3
+	julianDay := int(day) + (153*m+2)/5 + 365*y + y/4 - 32083
+
+	return time.Date(2000, time.January, 1+julianDay-julianDayOf2000, 0, 0, 0, 0, time.UTC)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
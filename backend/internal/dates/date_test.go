@@ -0,0 +1,199 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func day(year int, month time.Month, d int) *time.Time {
+	t := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     Date
+		earliest *time.Time
+		latest   *time.Time
+	}{
+		{"unknown", Date{}, nil, nil},
+		{"exact day", Date{Year: ptr[int32](1820), Month: ptr[int32](5), Day: ptr[int32](17)},
+			day(1820, time.May, 17), day(1820, time.May, 17)},
+		{"year only", Date{Year: ptr[int32](1820)}, day(1820, time.January, 1), day(1820, time.December, 31)},
+		{"month only", Date{Year: ptr[int32](1820), Month: ptr[int32](2)},
+			day(1820, time.February, 1), day(1820, time.February, 29)},
+		{"day without month", Date{Year: ptr[int32](1820), Day: ptr[int32](17)},
+			day(1820, time.January, 1), day(1820, time.December, 31)},
+		{"about year", Date{Year: ptr[int32](1820), Qualifier: ptr(QUALIFIER_ABOUT)},
+			day(1815, time.January, 1), day(1825, time.December, 31)},
+		{"about day", Date{Year: ptr[int32](1820), Month: ptr[int32](5), Day: ptr[int32](17), Qualifier: ptr(QUALIFIER_ABOUT)},
+			day(1815, time.May, 17), day(1825, time.May, 17)},
+		{"before year", Date{Year: ptr[int32](1750), Qualifier: ptr(QUALIFIER_BEFORE)},
+			nil, day(1749, time.December, 31)},
+		{"before day", Date{Year: ptr[int32](1750), Month: ptr[int32](3), Day: ptr[int32](1), Qualifier: ptr(QUALIFIER_BEFORE)},
+			nil, day(1750, time.February, 28)},
+		{"after year", Date{Year: ptr[int32](1750), Qualifier: ptr(QUALIFIER_AFTER)},
+			day(1751, time.January, 1), nil},
+		{"after month", Date{Year: ptr[int32](1750), Month: ptr[int32](6), Qualifier: ptr(QUALIFIER_AFTER)},
+			day(1750, time.July, 1), nil},
+		{"between years", Date{Year: ptr[int32](1800), Qualifier: ptr(QUALIFIER_BETWEEN), ToYear: ptr[int32](1810)},
+			day(1800, time.January, 1), day(1810, time.December, 31)},
+		{"between months", Date{Year: ptr[int32](1800), Month: ptr[int32](3), Qualifier: ptr(QUALIFIER_BETWEEN),
+			ToYear: ptr[int32](1800), ToMonth: ptr[int32](4)},
+			day(1800, time.March, 1), day(1800, time.April, 30)},
+		{"between without end", Date{Year: ptr[int32](1800), Qualifier: ptr(QUALIFIER_BETWEEN)},
+			day(1800, time.January, 1), day(1800, time.December, 31)},
+		{"julian leap day", Date{Year: ptr[int32](1700), Month: ptr[int32](2), Day: ptr[int32](29), Calendar: ptr(CALENDAR_JULIAN)},
+			day(1700, time.March, 11), day(1700, time.March, 11)},
+		{"julian month", Date{Year: ptr[int32](1700), Month: ptr[int32](2), Calendar: ptr(CALENDAR_JULIAN)},
+			day(1700, time.February, 11), day(1700, time.March, 11)},
+		{"julian before gregorian reform", Date{Year: ptr[int32](1582), Month: ptr[int32](10), Day: ptr[int32](4), Calendar: ptr(CALENDAR_JULIAN)},
+			day(1582, time.October, 14), day(1582, time.October, 14)},
+		{"explicit gregorian", Date{Year: ptr[int32](1700), Month: ptr[int32](2), Calendar: ptr(CALENDAR_GREGORIAN)},
+			day(1700, time.February, 1), day(1700, time.February, 28)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earliest, latest := tt.date.Bounds()
+			if !equalDay(earliest, tt.earliest) {
+				t.Errorf("earliest = %v, want %v", formatDay(earliest), formatDay(tt.earliest))
+			}
+			if !equalDay(latest, tt.latest) {
+				t.Errorf("latest = %v, want %v", formatDay(latest), formatDay(tt.latest))
+			}
+		})
+	}
+}
+
+func TestDaysInMonth(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar *string
+		year     int32
+		month    int32
+		want     int32
+	}{
+		{"gregorian february", nil, 1821, 2, 28},
+		{"gregorian leap year", nil, 1820, 2, 29},
+		{"gregorian century", nil, 1700, 2, 28},
+		{"gregorian 400 years", nil, 2000, 2, 29},
+		{"julian century", ptr(CALENDAR_JULIAN), 1700, 2, 29},
+		{"julian february", ptr(CALENDAR_JULIAN), 1701, 2, 28},
+		{"julian april", ptr(CALENDAR_JULIAN), 1700, 4, 30},
+		{"december", nil, 1700, 12, 31},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DaysInMonth(tt.calendar, tt.year, tt.month); got != tt.want {
+				t.Errorf("DaysInMonth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgeRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   Date
+		to     Date
+		minAge *int32
+		maxAge *int32
+	}{
+		{"exact dates before birthday", Date{Year: ptr[int32](1900), Month: ptr[int32](6), Day: ptr[int32](15)},
+			Date{Year: ptr[int32](1950), Month: ptr[int32](6), Day: ptr[int32](14)}, ptr[int32](49), ptr[int32](49)},
+		{"exact dates on birthday", Date{Year: ptr[int32](1900), Month: ptr[int32](6), Day: ptr[int32](15)},
+			Date{Year: ptr[int32](1950), Month: ptr[int32](6), Day: ptr[int32](15)}, ptr[int32](50), ptr[int32](50)},
+		{"years only", Date{Year: ptr[int32](1900)}, Date{Year: ptr[int32](1950)}, ptr[int32](49), ptr[int32](50)},
+		{"about", Date{Year: ptr[int32](1900), Qualifier: ptr(QUALIFIER_ABOUT)}, Date{Year: ptr[int32](1950)},
+			ptr[int32](44), ptr[int32](55)},
+		{"from after", Date{Year: ptr[int32](1900), Qualifier: ptr(QUALIFIER_AFTER)}, Date{Year: ptr[int32](1950)},
+			nil, ptr[int32](49)},
+		{"from before", Date{Year: ptr[int32](1900), Qualifier: ptr(QUALIFIER_BEFORE)}, Date{Year: ptr[int32](1950)},
+			ptr[int32](50), nil},
+		{"to after", Date{Year: ptr[int32](1900)}, Date{Year: ptr[int32](1950), Qualifier: ptr(QUALIFIER_AFTER)},
+			ptr[int32](50), nil},
+		{"to before", Date{Year: ptr[int32](1900)}, Date{Year: ptr[int32](1950), Qualifier: ptr(QUALIFIER_BEFORE)},
+			nil, ptr[int32](49)},
+		{"both open-ended", Date{Year: ptr[int32](1900), Qualifier: ptr(QUALIFIER_AFTER)},
+			Date{Year: ptr[int32](1950), Qualifier: ptr(QUALIFIER_AFTER)}, nil, nil},
+		{"unknown to", Date{Year: ptr[int32](1900)}, Date{}, nil, nil},
+		{"to before from", Date{Year: ptr[int32](1950)}, Date{Year: ptr[int32](1900)}, ptr[int32](0), ptr[int32](0)},
+		{"julian to gregorian", Date{Year: ptr[int32](1700), Month: ptr[int32](2), Day: ptr[int32](29), Calendar: ptr(CALENDAR_JULIAN)},
+			Date{Year: ptr[int32](1701), Month: ptr[int32](3), Day: ptr[int32](10)}, ptr[int32](0), ptr[int32](0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minAge, maxAge := AgeRange(tt.from, tt.to)
+			if !equalAge(minAge, tt.minAge) {
+				t.Errorf("minAge = %v, want %v", formatAge(minAge), formatAge(tt.minAge))
+			}
+			if !equalAge(maxAge, tt.maxAge) {
+				t.Errorf("maxAge = %v, want %v", formatAge(maxAge), formatAge(tt.maxAge))
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Date
+		b    Date
+		want int
+	}{
+		{"earlier year", Date{Year: ptr[int32](1800)}, Date{Year: ptr[int32](1801)}, -1},
+		{"same year", Date{Year: ptr[int32](1800)}, Date{Year: ptr[int32](1800)}, 0},
+		{"unknown last", Date{}, Date{Year: ptr[int32](1800)}, 1},
+		{"both unknown", Date{}, Date{}, 0},
+		{"before sorts before its year", Date{Year: ptr[int32](1800), Qualifier: ptr(QUALIFIER_BEFORE)},
+			Date{Year: ptr[int32](1800)}, -1},
+		{"after sorts after its year", Date{Year: ptr[int32](1800), Qualifier: ptr(QUALIFIER_AFTER)},
+			Date{Year: ptr[int32](1800)}, 1},
+		{"between by its middle", Date{Year: ptr[int32](1790), Qualifier: ptr(QUALIFIER_BETWEEN), ToYear: ptr[int32](1810)},
+			Date{Year: ptr[int32](1799)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func formatDay(t *time.Time) string {
+	if t == nil {
+		return "unbounded"
+	}
+	return t.Format(time.DateOnly)
+}
+
+func equalAge(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func formatAge(age *int32) any {
+	if age == nil {
+		return "unbounded"
+	}
+	return *age
+}
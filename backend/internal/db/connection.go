@@ -3,6 +3,7 @@ package db
 import (
 	"log"
 	"os"
+	"time"
 
 	"database/sql"
 
@@ -10,7 +11,9 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-func ConnectToKuzu(path string) (*kuzu.Database, *kuzu.Connection) {
+// ConnectToKuzu opens the database with a pool of poolSize connections, where statements wait up to poolTimeout for
+// a free connection
+func ConnectToKuzu(path string, poolSize int, poolTimeout time.Duration) (*kuzu.Database, *KuzuPool) {
 	log.Println("[kuzu] Connecting to database...")
 	if _, err := os.Stat(path); err != nil {
 		log.Println("[kuzu] Database does not exist")
@@ -18,19 +21,20 @@ func ConnectToKuzu(path string) (*kuzu.Database, *kuzu.Connection) {
 	}
 
 	systemConfig := kuzu.DefaultSystemConfig()
-	systemConfig.BufferPoolSize = 1024 * 1024 * 50 // 50 MB buffer
+	// Every connection may run a query concurrently, which all share the buffer pool
+	systemConfig.BufferPoolSize = max(1024*1024*50, uint64(poolSize)*1024*1024*16) // at least 50 MB buffer
 	db, err := kuzu.OpenDatabase(path, systemConfig)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	conn, err := kuzu.OpenConnection(db)
+	pool, err := NewKuzuPool(db, poolSize, poolTimeout)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("[kuzu] Successfully connected to database")
+	log.Printf("[kuzu] Successfully connected to database with %d connections", poolSize)
 
-	return db, conn
+	return db, pool
 }
 
 func ConnectToSqlite(path string) *sql.DB {
@@ -40,7 +44,8 @@ func ConnectToSqlite(path string) *sql.DB {
 		log.Fatal(err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// Concurrent writes, e.g. parallel refreshes of the same session, wait for each other instead of failing
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,143 @@
+package db_test
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
+	"github.com/google/uuid"
+	"github.com/kuzudb/go-kuzu"
+)
+
+// The benchmarks measure the family tree queries against an existing database, e.g. a synthetic one created via
+// `dbsetup generate-synthetic`, and are skipped if there is none. As a baseline, BenchmarkFullGraph measures loading
+// the whole graph, which is what every request had to do before the filtering was pushed into Kuzu.
+var (
+	benchKuzuPath = flag.String("kuzu-path", "../../cmd/dbsetup/synthetic.kuzu", "Path to the kuzu database to benchmark")
+	benchPoolSize = flag.Int("pool-size", 4, "Number of kuzu connections, i.e. how many sub-queries run in parallel")
+	benchSamples  = flag.Int("samples", 20, "Number of random root persons, which are requested in turn")
+	benchSeed     = flag.Uint64("seed", 1, "Seed for choosing the root persons")
+)
+
+var benchDistances = []int{1, 2, 3, 5, 8}
+
+var (
+	benchDb    *kuzu.Database
+	benchPool  *db.KuzuPool
+	benchRoots []uuid.UUID
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	code := m.Run()
+	if benchPool != nil {
+		benchPool.Close()
+		benchDb.Close()
+	}
+	os.Exit(code)
+}
+
+// openBenchmarkDatabase connects once for all benchmarks and chooses the root persons
+func openBenchmarkDatabase(b *testing.B) *db.KuzuPool {
+	b.Helper()
+	if benchPool != nil {
+		return benchPool
+	}
+	if _, err := os.Stat(*benchKuzuPath); err != nil {
+		b.Skipf("no database to benchmark at '%s'", *benchKuzuPath)
+	}
+
+	benchDb, benchPool = db.ConnectToKuzu(*benchKuzuPath, *benchPoolSize, time.Minute)
+	persons, err := db.GetAllPersons(benchPool)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(*benchSeed, *benchSeed))
+	benchRoots = make([]uuid.UUID, *benchSamples)
+	for i := range benchRoots {
+		benchRoots[i] = persons[rng.IntN(len(persons))].Id
+	}
+	return benchPool
+}
+
+func newBenchmarkService(pool *db.KuzuPool) (*service.FamilyTreeService, service.Viewer) {
+	// Admins see all persons unredacted, so only the queries themselves are measured
+	viewer := service.Viewer{Role: "admin"}
+	access := service.NewAccessPolicy(nil, pool, service.AccessConfig{PrivacyMinRole: viewer.Role})
+	return service.NewFamilyTreeService(pool, access, nil, nil), viewer
+}
+
+// BenchmarkFullGraph loads all persons and relations, as GetFamilyTree did per request before
+func BenchmarkFullGraph(b *testing.B) {
+	pool := openBenchmarkDatabase(b)
+
+	persons := 0
+	for b.Loop() {
+		all, err := db.GetAllPersons(pool)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.GetAllMarriageRelations(pool); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.GetAllParentRelations(pool); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.GetAllSiblingRelations(pool); err != nil {
+			b.Fatal(err)
+		}
+		persons = len(all)
+	}
+	b.ReportMetric(float64(persons), "persons/op")
+}
+
+func BenchmarkFamilyTree(b *testing.B) {
+	familyTreeService, viewer := newBenchmarkService(openBenchmarkDatabase(b))
+
+	for _, distance := range benchDistances {
+		b.Run(fmt.Sprintf("distance=%d", distance), func(b *testing.B) {
+			benchmarkRoots(b, func(id uuid.UUID) (int, error) {
+				dto, err := familyTreeService.GetFamilyTree(id, distance, viewer)
+				if err != nil {
+					return 0, err
+				}
+				return len(dto.Persons), nil
+			})
+		})
+	}
+}
+
+func BenchmarkAncestorTree(b *testing.B) {
+	familyTreeService, viewer := newBenchmarkService(openBenchmarkDatabase(b))
+
+	for _, generations := range benchDistances {
+		b.Run(fmt.Sprintf("generations=%d", generations), func(b *testing.B) {
+			benchmarkRoots(b, func(id uuid.UUID) (int, error) {
+				dto, err := familyTreeService.GetAncestorTree(id, generations, viewer)
+				if err != nil {
+					return 0, err
+				}
+				return len(dto.Persons), nil
+			})
+		})
+	}
+}
+
+// benchmarkRoots requests the root persons in turn and reports the average number of persons returned
+func benchmarkRoots(b *testing.B, run func(id uuid.UUID) (int, error)) {
+	persons, requests := 0, 0
+	for b.Loop() {
+		n, err := run(benchRoots[requests%len(benchRoots)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		persons += n
+		requests++
+	}
+	b.ReportMetric(float64(persons)/float64(requests), "persons/op")
+}
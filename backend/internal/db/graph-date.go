@@ -0,0 +1,29 @@
+package db
+
+import "github.com/Sakrafux/family-tree-app/backend/internal/dates"
+
+func (p *Person) BirthDate() dates.Date {
+	return dates.Date{
+		Year: p.BirthDateYear, Month: p.BirthDateMonth, Day: p.BirthDateDay, Qualifier: p.BirthDateQualifier,
+		ToYear: p.BirthDateToYear, ToMonth: p.BirthDateToMonth, ToDay: p.BirthDateToDay, Calendar: p.BirthDateCalendar,
+	}
+}
+
+func (p *Person) SetBirthDate(date dates.Date) {
+	p.BirthDateYear, p.BirthDateMonth, p.BirthDateDay = date.Year, date.Month, date.Day
+	p.BirthDateQualifier, p.BirthDateCalendar = date.Qualifier, date.Calendar
+	p.BirthDateToYear, p.BirthDateToMonth, p.BirthDateToDay = date.ToYear, date.ToMonth, date.ToDay
+}
+
+func (p *Person) DeathDate() dates.Date {
+	return dates.Date{
+		Year: p.DeathDateYear, Month: p.DeathDateMonth, Day: p.DeathDateDay, Qualifier: p.DeathDateQualifier,
+		ToYear: p.DeathDateToYear, ToMonth: p.DeathDateToMonth, ToDay: p.DeathDateToDay, Calendar: p.DeathDateCalendar,
+	}
+}
+
+func (p *Person) SetDeathDate(date dates.Date) {
+	p.DeathDateYear, p.DeathDateMonth, p.DeathDateDay = date.Year, date.Month, date.Day
+	p.DeathDateQualifier, p.DeathDateCalendar = date.Qualifier, date.Calendar
+	p.DeathDateToYear, p.DeathDateToMonth, p.DeathDateToDay = date.ToYear, date.ToMonth, date.ToDay
+}
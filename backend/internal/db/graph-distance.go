@@ -0,0 +1,12 @@
+package db
+
+// PersonDistance is a person together with its distance to the root person of a query
+type PersonDistance struct {
+	*Person
+	Distance int64
+}
+
+// castPersonDistance cannot be generated, as the generator does not support embedded structs
+func castPersonDistance(data map[string]any) *PersonDistance {
+	return &PersonDistance{Person: CastPerson(data), Distance: data["distance"].(int64)}
+}
@@ -6,20 +6,81 @@ import (
 	"github.com/google/uuid"
 )
 
+// Person has its dates of birth and death flattened into the columns of dates.Date, see BirthDate and DeathDate
 type Person struct {
-	Id             uuid.UUID `cast-source:"id"`
-	FirstName      *string   `cast-source:"first_name"`
-	MiddleName     *string   `cast-source:"middle_name"`
-	LastName       *string   `cast-source:"last_name"`
-	BirthName      *string   `cast-source:"birth_name"`
-	Gender         *string   `cast-source:"gender"`
-	IsDead         *bool     `cast-source:"is_dead"`
-	BirthDateYear  *int32    `cast-source:"birth_date_year"`
-	BirthDateMonth *int32    `cast-source:"birth_date_month"`
-	BirthDateDay   *int32    `cast-source:"birth_date_day"`
-	DeathDateYear  *int32    `cast-source:"death_date_year"`
-	DeathDateMonth *int32    `cast-source:"death_date_month"`
-	DeathDateDay   *int32    `cast-source:"death_date_day"`
+	Id                 uuid.UUID `cast-source:"id"`
+	FirstName          *string   `cast-source:"first_name"`
+	MiddleName         *string   `cast-source:"middle_name"`
+	LastName           *string   `cast-source:"last_name"`
+	BirthName          *string   `cast-source:"birth_name"`
+	Gender             *string   `cast-source:"gender"`
+	IsDead             *bool     `cast-source:"is_dead"`
+	BirthDateYear      *int32    `cast-source:"birth_date_year"`
+	BirthDateMonth     *int32    `cast-source:"birth_date_month"`
+	BirthDateDay       *int32    `cast-source:"birth_date_day"`
+	BirthDateQualifier *string   `cast-source:"birth_date_qualifier"`
+	BirthDateToYear    *int32    `cast-source:"birth_date_to_year"`
+	BirthDateToMonth   *int32    `cast-source:"birth_date_to_month"`
+	BirthDateToDay     *int32    `cast-source:"birth_date_to_day"`
+	BirthDateCalendar  *string   `cast-source:"birth_date_calendar"`
+	DeathDateYear      *int32    `cast-source:"death_date_year"`
+	DeathDateMonth     *int32    `cast-source:"death_date_month"`
+	DeathDateDay       *int32    `cast-source:"death_date_day"`
+	DeathDateQualifier *string   `cast-source:"death_date_qualifier"`
+	DeathDateToYear    *int32    `cast-source:"death_date_to_year"`
+	DeathDateToMonth   *int32    `cast-source:"death_date_to_month"`
+	DeathDateToDay     *int32    `cast-source:"death_date_to_day"`
+	DeathDateCalendar  *string   `cast-source:"death_date_calendar"`
+}
+
+// Event is a dated occurrence in the life of a person, e.g. a baptism or an emigration. Place is the place as written
+// in the source, while PlaceId links the place of the gazetteer.
+type Event struct {
+	Id          uuid.UUID  `cast-source:"id"`
+	PersonId    uuid.UUID  `cast-source:"PersonId"`
+	Type        string     `cast-source:"event_type"`
+	DateYear    *int32     `cast-source:"date_year"`
+	DateMonth   *int32     `cast-source:"date_month"`
+	DateDay     *int32     `cast-source:"date_day"`
+	Place       *string    `cast-source:"place"`
+	PlaceId     *uuid.UUID `cast-source:"place_id"`
+	Description *string    `cast-source:"description"`
+}
+
+// Source is the evidence itself, e.g. a church register or a book
+type Source struct {
+	Id         uuid.UUID `cast-source:"id"`
+	Type       string    `cast-source:"source_type"`
+	Title      string    `cast-source:"title"`
+	Author     *string   `cast-source:"author"`
+	Repository *string   `cast-source:"repository"`
+	Url        *string   `cast-source:"url"`
+}
+
+// Citation is what a source states about a person, an event or a relation. As relations are edges, which cannot be
+// linked themselves, citations of relations are linked to both persons, while SubjectId identifies the relation.
+type Citation struct {
+	Id            uuid.UUID `cast-source:"id"`
+	SourceId      uuid.UUID `cast-source:"SourceId"`
+	SubjectType   string    `cast-source:"subject_type"`
+	SubjectId     string    `cast-source:"subject_id"`
+	Page          *string   `cast-source:"page"`
+	Quality       *int32    `cast-source:"quality"`
+	Transcription *string   `cast-source:"transcription"`
+}
+
+// Media is an uploaded file, e.g. a portrait photo or a scanned certificate, whose content is stored on disk under
+// its hash
+type Media struct {
+	Id           uuid.UUID `cast-source:"id"`
+	Hash         string    `cast-source:"hash"`
+	FileName     string    `cast-source:"file_name"`
+	ContentType  string    `cast-source:"content_type"`
+	Size         int64     `cast-source:"size"`
+	Width        *int32    `cast-source:"width"`
+	Height       *int32    `cast-source:"height"`
+	HasThumbnail bool      `cast-source:"has_thumbnail"`
+	Caption      *string   `cast-source:"caption"`
 }
 
 type MarriageKey struct {
@@ -53,8 +114,3 @@ type SiblingRelation struct {
 	Person2Id uuid.UUID `cast-source:"Person2Id"`
 	IsHalf    bool      `cast-source:"is_half"`
 }
-
-type GraphDistance struct {
-	Id       uuid.UUID `cast-source:"id"`
-	Distance int64     `cast-source:"distance"`
-}
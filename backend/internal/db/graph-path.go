@@ -0,0 +1,58 @@
+package db
+
+import "github.com/kuzudb/go-kuzu"
+
+type PathStep string
+
+const (
+	PATH_STEP_PARENT PathStep = "parent"
+	PATH_STEP_CHILD  PathStep = "child"
+	PATH_STEP_SPOUSE PathStep = "spouse"
+)
+
+// RelationshipPath is a walk through the graph, where Steps[i] describes how Persons[i+1] relates to Persons[i]
+type RelationshipPath struct {
+	Persons []*Person
+	Steps   []PathStep
+}
+
+// castRelationshipPath cannot be generated, as it has to resolve the node order of a recursive relationship,
+// which only contains the intermediate nodes and edges in arbitrary direction
+func castRelationshipPath(data map[string]any) *RelationshipPath {
+	start := data["a"].(kuzu.Node)
+	end := data["b"].(kuzu.Node)
+	rel := data["r"].(kuzu.RecursiveRelationship)
+
+	nodes := make(map[kuzu.InternalID]kuzu.Node, len(rel.Nodes)+2)
+	nodes[start.ID] = start
+	nodes[end.ID] = end
+	for _, node := range rel.Nodes {
+		nodes[node.ID] = node
+	}
+
+	path := &RelationshipPath{
+		Persons: []*Person{CastPerson(start.Properties)},
+		Steps:   make([]PathStep, 0, len(rel.Relationships)),
+	}
+	current := start.ID
+	for _, edge := range rel.Relationships {
+		forward := edge.SourceID == current
+		next := edge.DestinationID
+		if !forward {
+			next = edge.SourceID
+		}
+
+		switch {
+		case edge.Label == "IS_MARRIED":
+			path.Steps = append(path.Steps, PATH_STEP_SPOUSE)
+		case forward:
+			path.Steps = append(path.Steps, PATH_STEP_CHILD)
+		default:
+			path.Steps = append(path.Steps, PATH_STEP_PARENT)
+		}
+		path.Persons = append(path.Persons, CastPerson(nodes[next].Properties))
+		current = next
+	}
+
+	return path
+}
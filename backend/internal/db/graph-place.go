@@ -0,0 +1,36 @@
+package db
+
+import "github.com/google/uuid"
+
+// Place is an entry of the gazetteer, where historical names are other spellings of the very same place, e.g.
+// "Preßburg" for "Bratislava"
+type Place struct {
+	Id              uuid.UUID
+	ParentId        *uuid.UUID
+	Name            string
+	HistoricalNames []string
+	Latitude        *float64
+	Longitude       *float64
+}
+
+// castPlace cannot be generated, as the generator does not support lists
+func castPlace(data map[string]any) *Place {
+	place := &Place{Id: data["id"].(uuid.UUID), Name: data["name"].(string), HistoricalNames: make([]string, 0)}
+
+	if parentId, ok := data["ParentId"].(uuid.UUID); ok {
+		place.ParentId = &parentId
+	}
+	if names, ok := data["historical_names"].([]any); ok {
+		for _, name := range names {
+			place.HistoricalNames = append(place.HistoricalNames, name.(string))
+		}
+	}
+	if latitude, ok := data["latitude"].(float64); ok {
+		place.Latitude = &latitude
+	}
+	if longitude, ok := data["longitude"].(float64); ok {
+		place.Longitude = &longitude
+	}
+
+	return place
+}
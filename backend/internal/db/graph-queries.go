@@ -1,52 +1,953 @@
 package db
 
 import (
+	"fmt"
+
 	"github.com/google/uuid"
-	"github.com/kuzudb/go-kuzu"
 )
 
-func GetAllPersons(conn *kuzu.Connection) ([]*Person, error) {
-	query := `
-	MATCH (a:Person)
+const personReturn = `
 	RETURN a.id as id, a.first_name as first_name, a.middle_name as middle_name, a.last_name as last_name, 
 		a.birth_name as birth_name, a.gender as gender, a.is_dead as is_dead, 
 		a.birth_date_year as birth_date_year, a.birth_date_month as birth_date_month, a.birth_date_day as birth_date_day,
-		a.death_date_year as death_date_year, a.death_date_month as death_date_month, a.death_date_day as death_date_day
+		a.birth_date_qualifier as birth_date_qualifier, a.birth_date_to_year as birth_date_to_year,
+		a.birth_date_to_month as birth_date_to_month, a.birth_date_to_day as birth_date_to_day,
+		a.birth_date_calendar as birth_date_calendar,
+		a.death_date_year as death_date_year, a.death_date_month as death_date_month, a.death_date_day as death_date_day,
+		a.death_date_qualifier as death_date_qualifier, a.death_date_to_year as death_date_to_year,
+		a.death_date_to_month as death_date_to_month, a.death_date_to_day as death_date_to_day,
+		a.death_date_calendar as death_date_calendar
 	`
-	return executeQuery(conn, query, CastPerson)
-}
 
-func GetAllMarriageRelations(conn *kuzu.Connection) ([]*MarriageRelation, error) {
-	query := `
-	MATCH (a:Person)-[e:IS_MARRIED]->(b:Person)
+const marriageReturn = `
 	RETURN a.id as Person1Id, b.id as Person2Id, 
 		e.since_year as since_year, e.since_month as since_month, e.since_day as since_day,
 		e.until_year as until_year, e.until_month as until_month, e.until_day as until_day
 	`
-	return executeQuery(conn, query, CastMarriageRelation)
+
+const eventReturn = `
+	WITH a, ev
+	OPTIONAL MATCH (ev)-[:TOOK_PLACE_AT]->(evpl:Place)
+	RETURN ev.id as id, a.id as PersonId, ev.event_type as event_type,
+		ev.date_year as date_year, ev.date_month as date_month, ev.date_day as date_day,
+		ev.place as place, evpl.id as place_id, ev.description as description
+	`
+
+const sourceReturn = `
+	RETURN src.id as id, src.source_type as source_type, src.title as title, src.author as author,
+		src.repository as repository, src.url as url
+	`
+
+const citationReturn = `
+	WITH DISTINCT c
+	MATCH (c)-[:CITES]->(src:Source)
+	RETURN c.id as id, src.id as SourceId, c.subject_type as subject_type, c.subject_id as subject_id,
+		c.page as page, c.quality as quality, c.transcription as transcription
+	`
+
+const mediaReturn = `
+	RETURN m.id as id, m.hash as hash, m.file_name as file_name, m.content_type as content_type, m.size as size,
+		m.width as width, m.height as height, m.has_thumbnail as has_thumbnail, m.caption as caption
+	`
+
+const placeReturn = `
+	WITH pl
+	OPTIONAL MATCH (pl)-[:LOCATED_IN]->(parent:Place)
+	RETURN pl.id as id, parent.id as ParentId, pl.name as name, pl.historical_names as historical_names,
+		pl.latitude as latitude, pl.longitude as longitude
+	`
+
+func GetAllPersons(pool *KuzuPool) ([]*Person, error) {
+	query := `
+	MATCH (a:Person)
+	` + personReturn
+	return executeQuery(pool, query, CastPerson)
+}
+
+func GetPersonById(pool *KuzuPool, id uuid.UUID) (*Person, error) {
+	query := `
+	MATCH (a:Person {id: UUID($id)})
+	` + personReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, CastPerson)
+}
+
+func CreatePerson(pool *KuzuPool, person *Person) (*Person, error) {
+	query := `
+	CREATE (a:Person {
+		id: UUID($id), first_name: $first_name, middle_name: $middle_name, last_name: $last_name,
+		birth_name: $birth_name, gender: $gender, is_dead: $is_dead,
+		birth_date_year: $birth_date_year, birth_date_month: $birth_date_month, birth_date_day: $birth_date_day,
+		birth_date_qualifier: $birth_date_qualifier, birth_date_to_year: $birth_date_to_year,
+		birth_date_to_month: $birth_date_to_month, birth_date_to_day: $birth_date_to_day,
+		birth_date_calendar: $birth_date_calendar,
+		death_date_year: $death_date_year, death_date_month: $death_date_month, death_date_day: $death_date_day,
+		death_date_qualifier: $death_date_qualifier, death_date_to_year: $death_date_to_year,
+		death_date_to_month: $death_date_to_month, death_date_to_day: $death_date_to_day,
+		death_date_calendar: $death_date_calendar
+	})
+	` + personReturn
+	return executeWriteStatementSingle(pool, query, personParams(person), CastPerson)
+}
+
+func UpdatePerson(pool *KuzuPool, person *Person) (*Person, error) {
+	query := `
+	MATCH (a:Person {id: UUID($id)})
+	SET a.first_name = $first_name, a.middle_name = $middle_name, a.last_name = $last_name,
+		a.birth_name = $birth_name, a.gender = $gender, a.is_dead = $is_dead,
+		a.birth_date_year = $birth_date_year, a.birth_date_month = $birth_date_month, a.birth_date_day = $birth_date_day,
+		a.birth_date_qualifier = $birth_date_qualifier, a.birth_date_to_year = $birth_date_to_year,
+		a.birth_date_to_month = $birth_date_to_month, a.birth_date_to_day = $birth_date_to_day,
+		a.birth_date_calendar = $birth_date_calendar,
+		a.death_date_year = $death_date_year, a.death_date_month = $death_date_month, a.death_date_day = $death_date_day,
+		a.death_date_qualifier = $death_date_qualifier, a.death_date_to_year = $death_date_to_year,
+		a.death_date_to_month = $death_date_to_month, a.death_date_to_day = $death_date_to_day,
+		a.death_date_calendar = $death_date_calendar
+	` + personReturn
+	return executeWriteStatementSingle(pool, query, personParams(person), CastPerson)
+}
+
+// DeletePerson also deletes all events of the person and all citations of the person, its events and its relations,
+// as they cannot exist on their own
+func DeletePerson(pool *KuzuPool, id uuid.UUID) error {
+	return pool.Transaction(func(tx *KuzuPool) error {
+		query := `
+		MATCH (c:Citation)-[:SUPPORTS_PERSON|SUPPORTS_RELATION]->(a:Person {id: UUID($id)})
+		DETACH DELETE c
+		`
+		if err := executeStatement(tx, query, map[string]any{"id": id.String()}); err != nil {
+			return err
+		}
+
+		query = `
+		MATCH (a:Person {id: UUID($id)})-[:HAS_EVENT]->(ev:Event)<-[:SUPPORTS_EVENT]-(c:Citation)
+		DETACH DELETE c
+		`
+		if err := executeStatement(tx, query, map[string]any{"id": id.String()}); err != nil {
+			return err
+		}
+
+		query = `
+		MATCH (a:Person {id: UUID($id)})-[:HAS_EVENT]->(ev:Event)
+		DETACH DELETE ev
+		`
+		if err := executeStatement(tx, query, map[string]any{"id": id.String()}); err != nil {
+			return err
+		}
+
+		query = `
+		MATCH (a:Person {id: UUID($id)})
+		DETACH DELETE a
+		`
+		return executeStatement(tx, query, map[string]any{"id": id.String()})
+	})
+}
+
+func personParams(person *Person) map[string]any {
+	return map[string]any{
+		"id":                   person.Id.String(),
+		"first_name":           nullable(person.FirstName),
+		"middle_name":          nullable(person.MiddleName),
+		"last_name":            nullable(person.LastName),
+		"birth_name":           nullable(person.BirthName),
+		"gender":               nullable(person.Gender),
+		"is_dead":              nullable(person.IsDead),
+		"birth_date_year":      nullable(person.BirthDateYear),
+		"birth_date_month":     nullable(person.BirthDateMonth),
+		"birth_date_day":       nullable(person.BirthDateDay),
+		"birth_date_qualifier": nullable(person.BirthDateQualifier),
+		"birth_date_to_year":   nullable(person.BirthDateToYear),
+		"birth_date_to_month":  nullable(person.BirthDateToMonth),
+		"birth_date_to_day":    nullable(person.BirthDateToDay),
+		"birth_date_calendar":  nullable(person.BirthDateCalendar),
+		"death_date_year":      nullable(person.DeathDateYear),
+		"death_date_month":     nullable(person.DeathDateMonth),
+		"death_date_day":       nullable(person.DeathDateDay),
+		"death_date_qualifier": nullable(person.DeathDateQualifier),
+		"death_date_to_year":   nullable(person.DeathDateToYear),
+		"death_date_to_month":  nullable(person.DeathDateToMonth),
+		"death_date_to_day":    nullable(person.DeathDateToDay),
+		"death_date_calendar":  nullable(person.DeathDateCalendar),
+	}
+}
+
+func GetAllMarriageRelations(pool *KuzuPool) ([]*MarriageRelation, error) {
+	query := `
+	MATCH (a:Person)-[e:IS_MARRIED]->(b:Person)
+	` + marriageReturn
+	return executeQuery(pool, query, CastMarriageRelation)
 }
 
-func GetAllParentRelations(conn *kuzu.Connection) ([]*ParentRelation, error) {
+func GetAllParentRelations(pool *KuzuPool) ([]*ParentRelation, error) {
 	query := `
 	MATCH (a:Person)-[e:IS_PARENT_OF]->(b:Person)
 	RETURN a.id as ParentId, b.id as ChildId
 	`
-	return executeQuery(conn, query, CastParentRelation)
+	return executeQuery(pool, query, CastParentRelation)
 }
 
-func GetAllSiblingRelations(conn *kuzu.Connection) ([]*SiblingRelation, error) {
+func GetAllSiblingRelations(pool *KuzuPool) ([]*SiblingRelation, error) {
 	query := `
 	MATCH (a:Person)-[e:IS_SIBLING]->(b:Person)
 	RETURN a.id as Person1Id, b.id as Person2Id, e.is_half as is_half
 	`
-	return executeQuery(conn, query, CastSiblingRelation)
+	return executeQuery(pool, query, CastSiblingRelation)
 }
 
-func GetGraphDistancesForRootById(conn *kuzu.Connection, id uuid.UUID) ([]*GraphDistance, error) {
+// GetPersonsInScope returns all persons of the scope except the root, sorted by their distance to the root
+func GetPersonsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*PersonDistance, error) {
+	if scope.isRootOnly() {
+		return make([]*PersonDistance, 0), nil
+	}
 	query := `
-	MATCH (root:Person {id: UUID($id)})-[r* SHORTEST]-(other:Person)
-	RETURN other.id AS id, length(r) AS distance
+	MATCH ` + scope.match("a") + personReturn + `, length(r) AS distance
 	ORDER BY distance
 	`
-	return executePreparedStatement(conn, query, map[string]any{"id": id.String()}, CastGraphDistance)
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, castPersonDistance)
+}
+
+// GetMarriageRelationsInScope returns all marriages of the persons in the scope, including the root
+func GetMarriageRelationsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*MarriageRelation, error) {
+	query := scopedRelationQuery(scope, "IS_MARRIED", marriageReturn)
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastMarriageRelation)
+}
+
+// GetParentRelationsInScope returns all relations where a person in the scope, including the root, is either the
+// parent or the child
+func GetParentRelationsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*ParentRelation, error) {
+	query := scopedRelationQuery(scope, "IS_PARENT_OF", `
+	RETURN a.id as ParentId, b.id as ChildId
+	`)
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastParentRelation)
+}
+
+// GetSiblingRelationsInScope returns all sibling relations of the persons in the scope, including the root
+func GetSiblingRelationsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*SiblingRelation, error) {
+	query := scopedRelationQuery(scope, "IS_SIBLING", `
+	RETURN a.id as Person1Id, b.id as Person2Id, e.is_half as is_half
+	`)
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastSiblingRelation)
+}
+
+// GetShortestRelationshipPaths returns all shortest paths over parent and marriage relations between both persons.
+// Kuzu limits recursive relationships to 30 hops, which covers any relation that can still be named reasonably.
+func GetShortestRelationshipPaths(pool *KuzuPool, fromId, toId uuid.UUID) ([]*RelationshipPath, error) {
+	query := `
+	MATCH (a:Person {id: UUID($from)})-[r:IS_PARENT_OF|IS_MARRIED* ALL SHORTEST 1..%d]-(b:Person {id: UUID($to)})
+	RETURN a, b, r
+	`
+	query = fmt.Sprintf(query, MAX_GENERATIONS)
+	args := map[string]any{"from": fromId.String(), "to": toId.String()}
+	return executePreparedStatement(pool, query, args, castRelationshipPath)
+}
+
+func GetParentRelation(pool *KuzuPool, parentId, childId uuid.UUID) (*ParentRelation, error) {
+	query := `
+	MATCH (a:Person {id: UUID($parent)})-[e:IS_PARENT_OF]->(b:Person {id: UUID($child)})
+	RETURN a.id as ParentId, b.id as ChildId
+	`
+	args := map[string]any{"parent": parentId.String(), "child": childId.String()}
+	return executePreparedStatementSingle(pool, query, args, CastParentRelation)
+}
+
+func GetParentRelationsByChildId(pool *KuzuPool, childId uuid.UUID) ([]*ParentRelation, error) {
+	query := `
+	MATCH (a:Person)-[e:IS_PARENT_OF]->(b:Person {id: UUID($child)})
+	RETURN a.id as ParentId, b.id as ChildId
+	`
+	return executePreparedStatement(pool, query, map[string]any{"child": childId.String()}, CastParentRelation)
+}
+
+func GetParentRelationsByParentId(pool *KuzuPool, parentId uuid.UUID) ([]*ParentRelation, error) {
+	query := `
+	MATCH (a:Person {id: UUID($parent)})-[e:IS_PARENT_OF]->(b:Person)
+	RETURN a.id as ParentId, b.id as ChildId
+	`
+	return executePreparedStatement(pool, query, map[string]any{"parent": parentId.String()}, CastParentRelation)
+}
+
+func CreateParentRelation(pool *KuzuPool, relation *ParentRelation) error {
+	query := `
+	MATCH (a:Person {id: UUID($parent)}), (b:Person {id: UUID($child)})
+	CREATE (a)-[:IS_PARENT_OF]->(b)
+	`
+	args := map[string]any{"parent": relation.ParentId.String(), "child": relation.ChildId.String()}
+	return executeStatement(pool, query, args)
+}
+
+func DeleteParentRelation(pool *KuzuPool, parentId, childId uuid.UUID) error {
+	query := `
+	MATCH (a:Person {id: UUID($parent)})-[e:IS_PARENT_OF]->(b:Person {id: UUID($child)})
+	DELETE e
+	`
+	args := map[string]any{"parent": parentId.String(), "child": childId.String()}
+	return executeStatement(pool, query, args)
+}
+
+// GetMarriageRelation ignores the direction of the stored edge, as a marriage is symmetric.
+// The result however reflects the stored direction, which is required for updating or deleting the edge.
+func GetMarriageRelation(pool *KuzuPool, person1Id, person2Id uuid.UUID) (*MarriageRelation, error) {
+	query := `
+	MATCH (a:Person)-[e:IS_MARRIED]->(b:Person)
+	WHERE (a.id = UUID($person1) AND b.id = UUID($person2)) OR (a.id = UUID($person2) AND b.id = UUID($person1))
+	` + marriageReturn
+	args := map[string]any{"person1": person1Id.String(), "person2": person2Id.String()}
+	return executePreparedStatementSingle(pool, query, args, CastMarriageRelation)
+}
+
+// GetMarriageRelationsByPersonId returns the marriages in their stored direction, with the person on either side
+func GetMarriageRelationsByPersonId(pool *KuzuPool, id uuid.UUID) ([]*MarriageRelation, error) {
+	query := `
+	MATCH (a:Person)-[e:IS_MARRIED]->(b:Person)
+	WHERE a.id = UUID($id) OR b.id = UUID($id)
+	` + marriageReturn
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastMarriageRelation)
+}
+
+func CreateMarriageRelation(pool *KuzuPool, relation *MarriageRelation) (*MarriageRelation, error) {
+	query := `
+	MATCH (a:Person {id: UUID($person1)}), (b:Person {id: UUID($person2)})
+	CREATE (a)-[e:IS_MARRIED {
+		since_year: $since_year, since_month: $since_month, since_day: $since_day,
+		until_year: $until_year, until_month: $until_month, until_day: $until_day
+	}]->(b)
+	` + marriageReturn
+	return executeWriteStatementSingle(pool, query, marriageParams(relation), CastMarriageRelation)
+}
+
+func UpdateMarriageRelation(pool *KuzuPool, relation *MarriageRelation) (*MarriageRelation, error) {
+	query := `
+	MATCH (a:Person {id: UUID($person1)})-[e:IS_MARRIED]->(b:Person {id: UUID($person2)})
+	SET e.since_year = $since_year, e.since_month = $since_month, e.since_day = $since_day,
+		e.until_year = $until_year, e.until_month = $until_month, e.until_day = $until_day
+	` + marriageReturn
+	return executeWriteStatementSingle(pool, query, marriageParams(relation), CastMarriageRelation)
+}
+
+func DeleteMarriageRelation(pool *KuzuPool, person1Id, person2Id uuid.UUID) error {
+	query := `
+	MATCH (a:Person {id: UUID($person1)})-[e:IS_MARRIED]->(b:Person {id: UUID($person2)})
+	DELETE e
+	`
+	args := map[string]any{"person1": person1Id.String(), "person2": person2Id.String()}
+	return executeStatement(pool, query, args)
+}
+
+func marriageParams(relation *MarriageRelation) map[string]any {
+	return map[string]any{
+		"person1":     relation.Person1Id.String(),
+		"person2":     relation.Person2Id.String(),
+		"since_year":  nullable(relation.SinceYear),
+		"since_month": nullable(relation.SinceMonth),
+		"since_day":   nullable(relation.SinceDay),
+		"until_year":  nullable(relation.UntilYear),
+		"until_month": nullable(relation.UntilMonth),
+		"until_day":   nullable(relation.UntilDay),
+	}
+}
+
+// RecomputeSiblingRelations replaces all IS_SIBLING edges of the given person with ones derived from the current
+// IS_PARENT_OF edges, which leaves all sibling relations not involving this person untouched
+func RecomputeSiblingRelations(pool *KuzuPool, id uuid.UUID) error {
+	return pool.Transaction(func(tx *KuzuPool) error {
+		deleteQuery := `
+		MATCH (a:Person)-[s:IS_SIBLING]->(b:Person)
+		WHERE a.id = UUID($id) OR b.id = UUID($id)
+		DELETE s
+		`
+		if err := executeStatement(tx, deleteQuery, map[string]any{"id": id.String()}); err != nil {
+			return err
+		}
+
+		createQuery := `
+		MATCH (p1:Person)<-[:IS_PARENT_OF]-(parent)-[:IS_PARENT_OF]->(p2:Person)
+		WHERE id(p1) < id(p2) AND (p1.id = UUID($id) OR p2.id = UUID($id))
+		WITH p1, p2, collect(DISTINCT parent) AS parents
+		MERGE (p1)-[s:IS_SIBLING]->(p2)
+		SET s.is_half = CASE WHEN size(parents) = 1 THEN true ELSE false END
+		`
+		return executeStatement(tx, createQuery, map[string]any{"id": id.String()})
+	})
+}
+
+// RebuildAllSiblingRelations drops every IS_SIBLING edge and derives them anew, analogous to the initial migration
+func RebuildAllSiblingRelations(pool *KuzuPool) error {
+	return pool.Transaction(func(tx *KuzuPool) error {
+		deleteQuery := `
+		MATCH (:Person)-[s:IS_SIBLING]->(:Person)
+		DELETE s
+		`
+		if err := executeStatement(tx, deleteQuery, make(map[string]any)); err != nil {
+			return err
+		}
+
+		createQuery := `
+		MATCH (p1:Person)<-[:IS_PARENT_OF]-(parent)-[:IS_PARENT_OF]->(p2:Person)
+		WHERE id(p1) < id(p2)
+		WITH p1, p2, collect(DISTINCT parent) AS parents
+		MERGE (p1)-[s:IS_SIBLING]->(p2)
+		SET s.is_half = CASE WHEN size(parents) = 1 THEN true ELSE false END
+		`
+		return executeStatement(tx, createQuery, make(map[string]any))
+	})
+}
+
+// GetEventsInScope returns the events of all persons in the scope, including the root
+func GetEventsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*Event, error) {
+	query := `MATCH (a:Person {id: UUID($id)})-[:HAS_EVENT]->(ev:Event)` + eventReturn
+	if !scope.isRootOnly() {
+		query += "UNION\n" + `MATCH ` + scope.match("a") + ` WITH DISTINCT a MATCH (a)-[:HAS_EVENT]->(ev:Event)` + eventReturn
+	}
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastEvent)
+}
+
+func GetEventById(pool *KuzuPool, id uuid.UUID) (*Event, error) {
+	query := `
+	MATCH (a:Person)-[:HAS_EVENT]->(ev:Event {id: UUID($id)})
+	` + eventReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, CastEvent)
+}
+
+func GetEventsByPersonId(pool *KuzuPool, personId uuid.UUID) ([]*Event, error) {
+	query := `
+	MATCH (a:Person {id: UUID($person)})-[:HAS_EVENT]->(ev:Event)
+	` + eventReturn
+	return executePreparedStatement(pool, query, map[string]any{"person": personId.String()}, CastEvent)
+}
+
+func CreateEvent(pool *KuzuPool, event *Event) (*Event, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Event, error) {
+		query := `
+		MATCH (a:Person {id: UUID($person)})
+		CREATE (a)-[:HAS_EVENT]->(ev:Event {
+			id: UUID($id), event_type: $event_type, date_year: $date_year, date_month: $date_month, date_day: $date_day,
+			place: $place, description: $description
+		})
+		`
+		if err := executeStatement(tx, query, eventParams(event)); err != nil {
+			return nil, err
+		}
+		if err := setEventPlace(tx, event.Id, event.PlaceId); err != nil {
+			return nil, err
+		}
+		return GetEventById(tx, event.Id)
+	})
+}
+
+func UpdateEvent(pool *KuzuPool, event *Event) (*Event, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Event, error) {
+		query := `
+		MATCH (a:Person {id: UUID($person)})-[:HAS_EVENT]->(ev:Event {id: UUID($id)})
+		SET ev.event_type = $event_type, ev.date_year = $date_year, ev.date_month = $date_month, ev.date_day = $date_day,
+			ev.place = $place, ev.description = $description
+		`
+		if err := executeStatement(tx, query, eventParams(event)); err != nil {
+			return nil, err
+		}
+		if err := setEventPlace(tx, event.Id, event.PlaceId); err != nil {
+			return nil, err
+		}
+		return GetEventById(tx, event.Id)
+	})
+}
+
+// setEventPlace replaces the link to the gazetteer, where nil only removes it
+func setEventPlace(pool *KuzuPool, eventId uuid.UUID, placeId *uuid.UUID) error {
+	query := `
+	MATCH (ev:Event {id: UUID($id)})-[r:TOOK_PLACE_AT]->(:Place)
+	DELETE r
+	`
+	if err := executeStatement(pool, query, map[string]any{"id": eventId.String()}); err != nil {
+		return err
+	}
+	if placeId == nil {
+		return nil
+	}
+
+	query = `
+	MATCH (ev:Event {id: UUID($id)}), (pl:Place {id: UUID($place)})
+	CREATE (ev)-[:TOOK_PLACE_AT]->(pl)
+	`
+	return executeStatement(pool, query, map[string]any{"id": eventId.String(), "place": placeId.String()})
+}
+
+// DeleteEvent also deletes all citations of the event
+func DeleteEvent(pool *KuzuPool, id uuid.UUID) error {
+	return pool.Transaction(func(tx *KuzuPool) error {
+		query := `
+		MATCH (c:Citation)-[:SUPPORTS_EVENT]->(ev:Event {id: UUID($id)})
+		DETACH DELETE c
+		`
+		if err := executeStatement(tx, query, map[string]any{"id": id.String()}); err != nil {
+			return err
+		}
+
+		query = `
+		MATCH (ev:Event {id: UUID($id)})
+		DETACH DELETE ev
+		`
+		return executeStatement(tx, query, map[string]any{"id": id.String()})
+	})
+}
+
+func eventParams(event *Event) map[string]any {
+	return map[string]any{
+		"id":          event.Id.String(),
+		"person":      event.PersonId.String(),
+		"event_type":  event.Type,
+		"date_year":   nullable(event.DateYear),
+		"date_month":  nullable(event.DateMonth),
+		"date_day":    nullable(event.DateDay),
+		"place":       nullable(event.Place),
+		"description": nullable(event.Description),
+	}
+}
+
+func GetAllPlaces(pool *KuzuPool) ([]*Place, error) {
+	query := `
+	MATCH (pl:Place)
+	` + placeReturn
+	return executeQuery(pool, query, castPlace)
+}
+
+func GetPlaceById(pool *KuzuPool, id uuid.UUID) (*Place, error) {
+	query := `
+	MATCH (pl:Place {id: UUID($id)})
+	` + placeReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, castPlace)
+}
+
+func GetPlacesByParentId(pool *KuzuPool, parentId uuid.UUID) ([]*Place, error) {
+	query := `
+	MATCH (pl:Place)-[:LOCATED_IN]->(:Place {id: UUID($parent)})
+	` + placeReturn
+	return executePreparedStatement(pool, query, map[string]any{"parent": parentId.String()}, castPlace)
+}
+
+func CreatePlace(pool *KuzuPool, place *Place) (*Place, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Place, error) {
+		query := `
+		CREATE (pl:Place {
+			id: UUID($id), name: $name, historical_names: $historical_names, latitude: $latitude, longitude: $longitude
+		})
+		`
+		if err := executeStatement(tx, query, placeParams(place)); err != nil {
+			return nil, err
+		}
+		if err := setPlaceParent(tx, place.Id, place.ParentId); err != nil {
+			return nil, err
+		}
+		return GetPlaceById(tx, place.Id)
+	})
+}
+
+func UpdatePlace(pool *KuzuPool, place *Place) (*Place, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Place, error) {
+		query := `
+		MATCH (pl:Place {id: UUID($id)})
+		SET pl.name = $name, pl.historical_names = $historical_names, pl.latitude = $latitude, pl.longitude = $longitude
+		`
+		if err := executeStatement(tx, query, placeParams(place)); err != nil {
+			return nil, err
+		}
+		if err := setPlaceParent(tx, place.Id, place.ParentId); err != nil {
+			return nil, err
+		}
+		return GetPlaceById(tx, place.Id)
+	})
+}
+
+// setPlaceParent replaces the place within which the place is located, where nil only removes it
+func setPlaceParent(pool *KuzuPool, id uuid.UUID, parentId *uuid.UUID) error {
+	query := `
+	MATCH (pl:Place {id: UUID($id)})-[r:LOCATED_IN]->(:Place)
+	DELETE r
+	`
+	if err := executeStatement(pool, query, map[string]any{"id": id.String()}); err != nil {
+		return err
+	}
+	if parentId == nil {
+		return nil
+	}
+
+	query = `
+	MATCH (pl:Place {id: UUID($id)}), (parent:Place {id: UUID($parent)})
+	CREATE (pl)-[:LOCATED_IN]->(parent)
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String(), "parent": parentId.String()})
+}
+
+func DeletePlace(pool *KuzuPool, id uuid.UUID) error {
+	query := `
+	MATCH (pl:Place {id: UUID($id)})
+	DETACH DELETE pl
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String()})
+}
+
+// MovePlaceReferences links all events and sub-places of the first place to the second place instead
+func MovePlaceReferences(pool *KuzuPool, fromId, toId uuid.UUID) error {
+	return pool.Transaction(func(tx *KuzuPool) error {
+		args := map[string]any{"from": fromId.String(), "to": toId.String()}
+
+		query := `
+		MATCH (ev:Event)-[r:TOOK_PLACE_AT]->(:Place {id: UUID($from)}), (to:Place {id: UUID($to)})
+		CREATE (ev)-[:TOOK_PLACE_AT]->(to)
+		DELETE r
+		`
+		if err := executeStatement(tx, query, args); err != nil {
+			return err
+		}
+
+		query = `
+		MATCH (pl:Place)-[r:LOCATED_IN]->(:Place {id: UUID($from)}), (to:Place {id: UUID($to)})
+		WHERE pl.id <> to.id
+		CREATE (pl)-[:LOCATED_IN]->(to)
+		DELETE r
+		`
+		return executeStatement(tx, query, args)
+	})
+}
+
+// GetEventsAtPlace returns the events which took place at the place or anywhere within it
+func GetEventsAtPlace(pool *KuzuPool, id uuid.UUID) ([]*Event, error) {
+	query := `
+	MATCH (a:Person)-[:HAS_EVENT]->(ev:Event)-[:TOOK_PLACE_AT]->(:Place {id: UUID($id)})
+	` + eventReturn + `
+	UNION
+	MATCH (a:Person)-[:HAS_EVENT]->(ev:Event)-[:TOOK_PLACE_AT]->(:Place)-[:LOCATED_IN*1..30]->(:Place {id: UUID($id)})
+	` + eventReturn
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastEvent)
+}
+
+// GetPersonsAtPlace returns the persons with any event at the place or anywhere within it
+func GetPersonsAtPlace(pool *KuzuPool, id uuid.UUID) ([]*Person, error) {
+	query := `
+	MATCH (a:Person)-[:HAS_EVENT]->(:Event)-[:TOOK_PLACE_AT]->(:Place {id: UUID($id)})
+	` + personReturn + `
+	UNION
+	MATCH (a:Person)-[:HAS_EVENT]->(:Event)-[:TOOK_PLACE_AT]->(:Place)-[:LOCATED_IN*1..30]->(:Place {id: UUID($id)})
+	` + personReturn
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastPerson)
+}
+
+func placeParams(place *Place) map[string]any {
+	// kuzu cannot bind empty lists, so no historical names are stored as NULL instead
+	var historicalNames any
+	if len(place.HistoricalNames) > 0 {
+		historicalNames = place.HistoricalNames
+	}
+	return map[string]any{
+		"id":               place.Id.String(),
+		"name":             place.Name,
+		"historical_names": historicalNames,
+		"latitude":         nullable(place.Latitude),
+		"longitude":        nullable(place.Longitude),
+	}
+}
+
+func GetAllSources(pool *KuzuPool) ([]*Source, error) {
+	query := `
+	MATCH (src:Source)
+	` + sourceReturn
+	return executeQuery(pool, query, CastSource)
+}
+
+func GetSourceById(pool *KuzuPool, id uuid.UUID) (*Source, error) {
+	query := `
+	MATCH (src:Source {id: UUID($id)})
+	` + sourceReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, CastSource)
+}
+
+func CreateSource(pool *KuzuPool, source *Source) (*Source, error) {
+	query := `
+	CREATE (src:Source {
+		id: UUID($id), source_type: $source_type, title: $title, author: $author, repository: $repository, url: $url
+	})
+	` + sourceReturn
+	return executeWriteStatementSingle(pool, query, sourceParams(source), CastSource)
+}
+
+func UpdateSource(pool *KuzuPool, source *Source) (*Source, error) {
+	query := `
+	MATCH (src:Source {id: UUID($id)})
+	SET src.source_type = $source_type, src.title = $title, src.author = $author, src.repository = $repository,
+		src.url = $url
+	` + sourceReturn
+	return executeWriteStatementSingle(pool, query, sourceParams(source), CastSource)
+}
+
+func DeleteSource(pool *KuzuPool, id uuid.UUID) error {
+	query := `
+	MATCH (src:Source {id: UUID($id)})
+	DETACH DELETE src
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String()})
+}
+
+func sourceParams(source *Source) map[string]any {
+	return map[string]any{
+		"id":          source.Id.String(),
+		"source_type": source.Type,
+		"title":       source.Title,
+		"author":      nullable(source.Author),
+		"repository":  nullable(source.Repository),
+		"url":         nullable(source.Url),
+	}
+}
+
+func GetCitationById(pool *KuzuPool, id uuid.UUID) (*Citation, error) {
+	query := `
+	MATCH (c:Citation {id: UUID($id)})
+	` + citationReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, CastCitation)
+}
+
+func GetCitationsBySourceId(pool *KuzuPool, sourceId uuid.UUID) ([]*Citation, error) {
+	query := `
+	MATCH (c:Citation)-[:CITES]->(:Source {id: UUID($source)})
+	` + citationReturn
+	return executePreparedStatement(pool, query, map[string]any{"source": sourceId.String()}, CastCitation)
+}
+
+// GetCitationsInScope returns the citations of all persons in the scope, including the root, of their events and of
+// their relations
+func GetCitationsInScope(pool *KuzuPool, id uuid.UUID, scope PersonScope) ([]*Citation, error) {
+	matches := []string{
+		`MATCH (c:Citation)-[:SUPPORTS_PERSON|SUPPORTS_RELATION]->(a)`,
+		`MATCH (c:Citation)-[:SUPPORTS_EVENT]->(:Event)<-[:HAS_EVENT]-(a)`,
+	}
+
+	query := ""
+	for i, match := range matches {
+		if i > 0 {
+			query += "UNION\n"
+		}
+		query += `MATCH (a:Person {id: UUID($id)}) ` + match + citationReturn
+		if !scope.isRootOnly() {
+			query += "UNION\n" + `MATCH ` + scope.match("a") + ` WITH DISTINCT a ` + match + citationReturn
+		}
+	}
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastCitation)
+}
+
+// CreatePersonCitation cites the source for the person itself
+func CreatePersonCitation(pool *KuzuPool, citation *Citation, personId uuid.UUID) (*Citation, error) {
+	query := `
+	MATCH (c:Citation {id: UUID($id)}), (a:Person {id: UUID($person)})
+	CREATE (c)-[:SUPPORTS_PERSON]->(a)
+	`
+	return createCitation(pool, citation, query, map[string]any{"person": personId.String()})
+}
+
+func CreateEventCitation(pool *KuzuPool, citation *Citation, eventId uuid.UUID) (*Citation, error) {
+	query := `
+	MATCH (c:Citation {id: UUID($id)}), (ev:Event {id: UUID($event)})
+	CREATE (c)-[:SUPPORTS_EVENT]->(ev)
+	`
+	return createCitation(pool, citation, query, map[string]any{"event": eventId.String()})
+}
+
+// CreateRelationCitation cites the source for the relation between both persons, where the kind of relation is
+// only known from the subject of the citation
+func CreateRelationCitation(pool *KuzuPool, citation *Citation, person1Id, person2Id uuid.UUID) (*Citation, error) {
+	query := `
+	MATCH (c:Citation {id: UUID($id)}), (a:Person)
+	WHERE a.id = UUID($person1) OR a.id = UUID($person2)
+	CREATE (c)-[:SUPPORTS_RELATION]->(a)
+	`
+	args := map[string]any{"person1": person1Id.String(), "person2": person2Id.String()}
+	return createCitation(pool, citation, query, args)
+}
+
+// createCitation creates the citation of its source, before the given statement links it to its subject
+func createCitation(pool *KuzuPool, citation *Citation, linkQuery string, linkArgs map[string]any) (*Citation, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Citation, error) {
+		query := `
+		MATCH (src:Source {id: UUID($source)})
+		CREATE (c:Citation {
+			id: UUID($id), subject_type: $subject_type, subject_id: $subject_id,
+			page: $page, quality: $quality, transcription: $transcription
+		})-[:CITES]->(src)
+		`
+		if err := executeStatement(tx, query, citationParams(citation)); err != nil {
+			return nil, err
+		}
+
+		linkArgs["id"] = citation.Id.String()
+		if err := executeStatement(tx, linkQuery, linkArgs); err != nil {
+			return nil, err
+		}
+		return GetCitationById(tx, citation.Id)
+	})
+}
+
+// UpdateCitation also moves the citation to another source, while the subject always stays the same
+func UpdateCitation(pool *KuzuPool, citation *Citation) (*Citation, error) {
+	return transaction(pool, func(tx *KuzuPool) (*Citation, error) {
+		query := `
+		MATCH (c:Citation {id: UUID($id)})-[r:CITES]->(:Source)
+		SET c.page = $page, c.quality = $quality, c.transcription = $transcription
+		DELETE r
+		`
+		args := citationParams(citation)
+		delete(args, "source")
+		delete(args, "subject_type")
+		delete(args, "subject_id")
+		if err := executeStatement(tx, query, args); err != nil {
+			return nil, err
+		}
+
+		query = `
+		MATCH (c:Citation {id: UUID($id)}), (src:Source {id: UUID($source)})
+		CREATE (c)-[:CITES]->(src)
+		`
+		args = map[string]any{"id": citation.Id.String(), "source": citation.SourceId.String()}
+		if err := executeStatement(tx, query, args); err != nil {
+			return nil, err
+		}
+		return GetCitationById(tx, citation.Id)
+	})
+}
+
+func DeleteCitation(pool *KuzuPool, id uuid.UUID) error {
+	query := `
+	MATCH (c:Citation {id: UUID($id)})
+	DETACH DELETE c
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String()})
+}
+
+// DeleteCitationsOfSubject is meant for relations, whose citations are not removed together with the edge
+func DeleteCitationsOfSubject(pool *KuzuPool, subjectType, subjectId string) error {
+	query := `
+	MATCH (c:Citation {subject_type: $subject_type, subject_id: $subject_id})
+	DETACH DELETE c
+	`
+	return executeStatement(pool, query, map[string]any{"subject_type": subjectType, "subject_id": subjectId})
+}
+
+func citationParams(citation *Citation) map[string]any {
+	return map[string]any{
+		"id":            citation.Id.String(),
+		"source":        citation.SourceId.String(),
+		"subject_type":  citation.SubjectType,
+		"subject_id":    citation.SubjectId,
+		"page":          nullable(citation.Page),
+		"quality":       nullable(citation.Quality),
+		"transcription": nullable(citation.Transcription),
+	}
+}
+
+func GetMediaById(pool *KuzuPool, id uuid.UUID) (*Media, error) {
+	query := `
+	MATCH (m:Media {id: UUID($id)})
+	` + mediaReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"id": id.String()}, CastMedia)
+}
+
+func GetMediaByHash(pool *KuzuPool, hash string) (*Media, error) {
+	query := `
+	MATCH (m:Media {hash: $hash})
+	` + mediaReturn
+	return executePreparedStatementSingle(pool, query, map[string]any{"hash": hash}, CastMedia)
+}
+
+func GetMediaByPersonId(pool *KuzuPool, personId uuid.UUID) ([]*Media, error) {
+	query := `
+	MATCH (m:Media)-[:ATTACHED_TO_PERSON]->(:Person {id: UUID($person)})
+	` + mediaReturn
+	return executePreparedStatement(pool, query, map[string]any{"person": personId.String()}, CastMedia)
+}
+
+func GetMediaByEventId(pool *KuzuPool, eventId uuid.UUID) ([]*Media, error) {
+	query := `
+	MATCH (m:Media)-[:ATTACHED_TO_EVENT]->(:Event {id: UUID($event)})
+	` + mediaReturn
+	return executePreparedStatement(pool, query, map[string]any{"event": eventId.String()}, CastMedia)
+}
+
+// GetPersonsOfMedia returns the persons the media is attached to, either directly or via one of their events
+func GetPersonsOfMedia(pool *KuzuPool, id uuid.UUID) ([]*Person, error) {
+	query := `
+	MATCH (:Media {id: UUID($id)})-[:ATTACHED_TO_PERSON]->(a:Person)
+	` + personReturn + `
+	UNION
+	MATCH (:Media {id: UUID($id)})-[:ATTACHED_TO_EVENT]->(:Event)<-[:HAS_EVENT]-(a:Person)
+	` + personReturn
+	return executePreparedStatement(pool, query, map[string]any{"id": id.String()}, CastPerson)
+}
+
+func CreateMedia(pool *KuzuPool, media *Media) (*Media, error) {
+	query := `
+	CREATE (m:Media {
+		id: UUID($id), hash: $hash, file_name: $file_name, content_type: $content_type, size: $size,
+		width: $width, height: $height, has_thumbnail: $has_thumbnail, caption: $caption
+	})
+	` + mediaReturn
+	return executeWriteStatementSingle(pool, query, mediaParams(media), CastMedia)
+}
+
+// UpdateMedia only updates the descriptive properties, as the content of a media never changes
+func UpdateMedia(pool *KuzuPool, media *Media) (*Media, error) {
+	query := `
+	MATCH (m:Media {id: UUID($id)})
+	SET m.file_name = $file_name, m.caption = $caption
+	` + mediaReturn
+	args := map[string]any{"id": media.Id.String(), "file_name": media.FileName, "caption": nullable(media.Caption)}
+	return executeWriteStatementSingle(pool, query, args, CastMedia)
+}
+
+func DeleteMedia(pool *KuzuPool, id uuid.UUID) error {
+	query := `
+	MATCH (m:Media {id: UUID($id)})
+	DETACH DELETE m
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String()})
+}
+
+func AttachMediaToPerson(pool *KuzuPool, id, personId uuid.UUID) error {
+	query := `
+	MATCH (m:Media {id: UUID($id)}), (a:Person {id: UUID($person)})
+	MERGE (m)-[:ATTACHED_TO_PERSON]->(a)
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String(), "person": personId.String()})
+}
+
+func DetachMediaFromPerson(pool *KuzuPool, id, personId uuid.UUID) error {
+	query := `
+	MATCH (:Media {id: UUID($id)})-[r:ATTACHED_TO_PERSON]->(:Person {id: UUID($person)})
+	DELETE r
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String(), "person": personId.String()})
+}
+
+func AttachMediaToEvent(pool *KuzuPool, id, eventId uuid.UUID) error {
+	query := `
+	MATCH (m:Media {id: UUID($id)}), (ev:Event {id: UUID($event)})
+	MERGE (m)-[:ATTACHED_TO_EVENT]->(ev)
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String(), "event": eventId.String()})
+}
+
+func DetachMediaFromEvent(pool *KuzuPool, id, eventId uuid.UUID) error {
+	query := `
+	MATCH (:Media {id: UUID($id)})-[r:ATTACHED_TO_EVENT]->(:Event {id: UUID($event)})
+	DELETE r
+	`
+	return executeStatement(pool, query, map[string]any{"id": id.String(), "event": eventId.String()})
+}
+
+func mediaParams(media *Media) map[string]any {
+	return map[string]any{
+		"id":            media.Id.String(),
+		"hash":          media.Hash,
+		"file_name":     media.FileName,
+		"content_type":  media.ContentType,
+		"size":          media.Size,
+		"width":         nullable(media.Width),
+		"height":        nullable(media.Height),
+		"has_thumbnail": media.HasThumbnail,
+		"caption":       nullable(media.Caption),
+	}
 }
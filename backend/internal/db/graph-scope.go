@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MAX_GENERATIONS is the upper bound Kuzu allows for recursive relationships
+const MAX_GENERATIONS = 30
+
+// PersonScope is the subgraph around a root person that a query is restricted to, so that only the relevant part
+// of the graph has to be loaded. The root is always bound via the $id parameter.
+type PersonScope struct {
+	// pattern binds the root to `root`, the path to `r` and the other persons to the variable given as %[1]s
+	pattern string
+}
+
+// personRelTables are all relations between persons, as opposed to relations to other nodes such as events
+const personRelTables = "IS_PARENT_OF|IS_MARRIED|IS_SIBLING"
+
+// ScopeWithinDistance contains all persons within maxDistance relations of any kind between persons
+func ScopeWithinDistance(maxDistance int) PersonScope {
+	if maxDistance < 1 {
+		return PersonScope{}
+	}
+	return PersonScope{fmt.Sprintf("(root:Person {id: UUID($id)})-[r:%s* SHORTEST 1..%d]-(%%[1]s:Person)", personRelTables, clampDistance(maxDistance))}
+}
+
+// ScopeAncestors contains all ancestors up to the given number of generations, so the distance is the generation
+func ScopeAncestors(generations int) PersonScope {
+	if generations < 1 {
+		return PersonScope{}
+	}
+	return PersonScope{fmt.Sprintf("(%%[1]s:Person)-[r:IS_PARENT_OF* SHORTEST 1..%d]->(root:Person {id: UUID($id)})", clampDistance(generations))}
+}
+
+// ScopeDescendants contains all descendants up to the given number of generations
+func ScopeDescendants(generations int) PersonScope {
+	if generations < 1 {
+		return PersonScope{}
+	}
+	return PersonScope{fmt.Sprintf("(root:Person {id: UUID($id)})-[r:IS_PARENT_OF* SHORTEST 1..%d]->(%%[1]s:Person)", clampDistance(generations))}
+}
+
+// Kuzu requires literal bounds for recursive relationships, so they are inlined instead of passed as parameters
+func clampDistance(distance int) int {
+	return min(distance, MAX_GENERATIONS)
+}
+
+func (s PersonScope) isRootOnly() bool {
+	return len(s.pattern) == 0
+}
+
+func (s PersonScope) match(variable string) string {
+	return fmt.Sprintf(s.pattern, variable)
+}
+
+// scopedRelationQuery returns all relations of the given table touching the scope in their stored direction.
+// Expanding from the matched nodes is much faster than looking up a list of ids, as Kuzu does not use the primary
+// key index for lists. Both directions are matched separately, as an undirected match would lose the stored
+// direction, and UNION removes relations between two persons of the scope that are found twice.
+func scopedRelationQuery(scope PersonScope, relTable, returnClause string) string {
+	parts := []string{
+		`MATCH (a:Person {id: UUID($id)})-[e:` + relTable + `]->(b:Person)` + returnClause,
+		`MATCH (a:Person)-[e:` + relTable + `]->(b:Person {id: UUID($id)})` + returnClause,
+	}
+	if !scope.isRootOnly() {
+		parts = append(parts,
+			`MATCH `+scope.match("a")+` WITH DISTINCT a MATCH (a)-[e:`+relTable+`]->(b:Person)`+returnClause,
+			`MATCH `+scope.match("b")+` WITH DISTINCT b MATCH (a:Person)-[e:`+relTable+`]->(b)`+returnClause,
+		)
+	}
+	return strings.Join(parts, "UNION\n")
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kuzudb/go-kuzu"
+)
+
+var ErrPoolTimeout = errors.New("timed out waiting for a free kuzu connection")
+
+// KuzuPool is a bounded pool of connections to a single kuzu database. A kuzu connection must not be used by
+// multiple goroutines at once, so every statement borrows a connection just for its own execution. Kuzu also rejects
+// a second concurrent write transaction instead of waiting for the first, so writers additionally queue up here.
+type KuzuPool struct {
+	conns   chan *kuzu.Connection
+	writer  chan struct{}
+	size    int
+	timeout time.Duration
+	// tx is only set on the view passed into Transaction, whose statements all run on this connection
+	tx *kuzu.Connection
+}
+
+func NewKuzuPool(db *kuzu.Database, size int, timeout time.Duration) (*KuzuPool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("kuzu pool size must be at least 1, but is %d", size)
+	}
+
+	pool := &KuzuPool{
+		conns:   make(chan *kuzu.Connection, size),
+		writer:  make(chan struct{}, 1),
+		size:    size,
+		timeout: timeout,
+	}
+	for range size {
+		conn, err := kuzu.OpenConnection(db)
+		if err != nil {
+			close(pool.conns)
+			for conn := range pool.conns {
+				conn.Close()
+			}
+			return nil, err
+		}
+		pool.conns <- conn
+	}
+
+	return pool, nil
+}
+
+// acquire waits up to the configured timeout for a free connection, which has to be released afterwards. As the
+// write slot is taken before the connection, a queued writer never blocks a connection that readers could use.
+func (p *KuzuPool) acquire(write bool) (*kuzu.Connection, error) {
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	if write {
+		select {
+		case p.writer <- struct{}{}:
+		case <-timer.C:
+			return nil, ErrPoolTimeout
+		}
+	}
+
+	select {
+	case conn := <-p.conns:
+		return conn, nil
+	case <-timer.C:
+		if write {
+			<-p.writer
+		}
+		return nil, ErrPoolTimeout
+	}
+}
+
+func (p *KuzuPool) release(conn *kuzu.Connection, write bool) {
+	p.conns <- conn
+	if write {
+		<-p.writer
+	}
+}
+
+// Transaction runs fn within a single write transaction, which holds one connection and the write slot until it is
+// committed, so that the checks and writes of a service operation cannot interleave with other writers. All
+// statements of fn have to go through the given pool, which must neither be shared with other goroutines nor be used
+// after fn returns. The transaction is rolled back if fn returns an error, and nested calls join the outer transaction.
+func (p *KuzuPool) Transaction(fn func(tx *KuzuPool) error) error {
+	if p.tx != nil {
+		return fn(p)
+	}
+
+	conn, err := p.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer p.release(conn, true)
+
+	if err := runQuery(conn, "BEGIN TRANSACTION"); err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		// Also covers a panic within fn, as the connection must not return to the pool with an open transaction
+		if !committed {
+			_ = runQuery(conn, "ROLLBACK")
+		}
+	}()
+
+	if err := fn(&KuzuPool{timeout: p.timeout, tx: conn}); err != nil {
+		return err
+	}
+	if err := runQuery(conn, "COMMIT"); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func runQuery(conn *kuzu.Connection, query string) error {
+	result, err := conn.Query(query)
+	if err != nil {
+		return err
+	}
+	result.Close()
+	return nil
+}
+
+// Close waits until all connections are released and closes them
+func (p *KuzuPool) Close() {
+	for range p.size {
+		conn := <-p.conns
+		conn.Close()
+	}
+}
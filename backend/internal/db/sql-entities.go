@@ -10,10 +10,59 @@ type Feedback struct {
 }
 
 type User struct {
-	Id       int
-	Username string
-	Password string
-	Salt     string
-	Role     string
-	NodeId   string
+	Id         int
+	Username   string
+	Password   string
+	Salt       string
+	Role       string
+	NodeId     string
+	IsDisabled bool
+}
+
+type Branch struct {
+	Id         int
+	Name       string
+	RootNodeId string
+	IsPublic   bool
+}
+
+// RefreshToken tracks an issued refresh token, where all tokens rotated from the same login share a family
+type RefreshToken struct {
+	Jti       string
+	UserId    int
+	Family    string
+	UsedAt    *time.Time
+	IsRevoked bool
+}
+
+type AuditEntry struct {
+	Id         int
+	Timestamp  time.Time
+	Username   string
+	Action     string
+	EntityType string
+	EntityId   string
+	Before     []byte
+	After      []byte
+}
+
+// AuditFilter restricts audit entries to those matching all non-empty fields
+type AuditFilter struct {
+	Username   string
+	EntityType string
+	EntityId   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+}
+
+// Revision is one version of a person or relation, where Data is nil if the entity has been deleted
+type Revision struct {
+	Id         int
+	EntityType string
+	EntityId   string
+	Version    int
+	Timestamp  time.Time
+	Username   string
+	Data       []byte
 }
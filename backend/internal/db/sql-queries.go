@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/security"
 )
@@ -30,6 +31,18 @@ func SelectAllFeedbacks(db *sql.DB) ([]*Feedback, error) {
 	return feedbacks, nil
 }
 
+func SelectFeedbackById(db *sql.DB, id int) (*Feedback, error) {
+	fb := &Feedback{}
+	var isResolvedInt int
+	err := db.QueryRow("SELECT id, text, creation_timestamp, is_resolved FROM feedback WHERE id = ?", id).
+		Scan(&fb.Id, &fb.Text, &fb.Timestamp, &isResolvedInt)
+	if err != nil {
+		return nil, err
+	}
+	fb.IsResolved = isResolvedInt != 0
+	return fb, nil
+}
+
 func InsertFeedback(db *sql.DB, text string) (*Feedback, error) {
 	res, err := db.Exec("INSERT INTO feedback (text) VALUES ($1)", text)
 	if err != nil {
@@ -54,21 +67,28 @@ func InsertFeedback(db *sql.DB, text string) (*Feedback, error) {
 }
 
 func UpdateFeedbackIsResolved(db *sql.DB, id int, isResolved bool) error {
-	isResolvedInt := 0
-	if isResolved {
-		isResolvedInt = 1
-	}
-	_, err := db.Exec("UPDATE feedback SET is_resolved = $1 WHERE id = $2", isResolvedInt, id)
+	_, err := db.Exec("UPDATE feedback SET is_resolved = $1 WHERE id = $2", boolToInt(isResolved), id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func GetUser(db *sql.DB, username, password string) (*User, error) {
+const selectUsers = "SELECT id, name, password, salt, role, node, is_disabled FROM users"
+
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
 	user := &User{}
-	err := db.QueryRow("SELECT id, name, password, salt, role, node FROM users WHERE name = $1", username).Scan(
-		&user.Id, &user.Username, &user.Password, &user.Salt, &user.Role, &user.NodeId)
+	var isDisabledInt int
+	err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Salt, &user.Role, &user.NodeId, &isDisabledInt)
+	if err != nil {
+		return nil, err
+	}
+	user.IsDisabled = isDisabledInt != 0
+	return user, nil
+}
+
+func GetUser(db *sql.DB, username, password string) (*User, error) {
+	user, err := GetUserByName(db, username)
 	if err != nil {
 		return nil, err
 	}
@@ -81,12 +101,482 @@ func GetUser(db *sql.DB, username, password string) (*User, error) {
 }
 
 func GetUserById(db *sql.DB, userId int) (*User, error) {
-	user := &User{}
-	err := db.QueryRow("SELECT id, name, password, salt, role, node FROM users WHERE id = $1", userId).Scan(
-		&user.Id, &user.Username, &user.Password, &user.Salt, &user.Role, &user.NodeId)
+	return scanUser(db.QueryRow(selectUsers+" WHERE id = $1", userId))
+}
+
+func GetUserByName(db *sql.DB, username string) (*User, error) {
+	return scanUser(db.QueryRow(selectUsers+" WHERE name = $1", username))
+}
+
+func SelectAllUsers(db *sql.DB) ([]*User, error) {
+	rows, err := db.Query(selectUsers + " ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return user, nil
+	users := make([]*User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func InsertUser(db *sql.DB, user *User) (*User, error) {
+	res, err := db.Exec(
+		"INSERT INTO users (name, password, salt, role, node, is_disabled) VALUES ($1, $2, $3, $4, $5, $6)",
+		user.Username, user.Password, user.Salt, user.Role, user.NodeId, boolToInt(user.IsDisabled),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetUserById(db, int(lastID))
+}
+
+// UpdateUser overwrites everything but the password, which is only changed together with its salt
+func UpdateUser(db *sql.DB, user *User) error {
+	_, err := db.Exec(
+		"UPDATE users SET name = $1, role = $2, node = $3, is_disabled = $4 WHERE id = $5",
+		user.Username, user.Role, user.NodeId, boolToInt(user.IsDisabled), user.Id,
+	)
+	return err
+}
+
+func UpdateUserPassword(db *sql.DB, id int, password, salt string) error {
+	_, err := db.Exec("UPDATE users SET password = $1, salt = $2 WHERE id = $3", password, salt, id)
+	return err
+}
+
+// DeleteUser deletes the user together with everything referring to it, all or nothing
+func DeleteUser(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rolling back after the commit does nothing
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM refresh_tokens WHERE user_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_branches WHERE user_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM users WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InsertPasswordResetToken replaces all unused tokens of the user, so that only the latest one handed out is valid
+func InsertPasswordResetToken(db *sql.DB, userId int, tokenHash string, expiresAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL", userId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		tokenHash, userId, expiresAt.UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumePasswordResetToken marks the token as used and returns its user, or sql.ErrNoRows if the token is unknown,
+// expired or already used. Marking and checking happen in a single statement, so a token can only be used once.
+func ConsumePasswordResetToken(db *sql.DB, tokenHash string) (int, error) {
+	var userId int
+	err := db.QueryRow(
+		"UPDATE password_reset_tokens SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id",
+		time.Now().UTC(), tokenHash,
+	).Scan(&userId)
+	return userId, err
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// InsertRefreshToken also cleans up all expired tokens, which are rejected by their signature anyway
+func InsertRefreshToken(db *sql.DB, jti string, userId int, family string, expiresAt time.Time) error {
+	if _, err := db.Exec("DELETE FROM refresh_tokens WHERE expires_at < $1", time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := db.Exec(
+		"INSERT INTO refresh_tokens (jti, user_id, family, expires_at) VALUES ($1, $2, $3, $4)",
+		jti, userId, family, expiresAt.UTC(),
+	)
+	return err
+}
+
+// UseRefreshToken marks the token as used and returns its family. If the token has been used or revoked before,
+// sql.ErrNoRows is returned instead, where checking and marking in one statement makes every token single-use.
+func UseRefreshToken(db *sql.DB, jti string) (string, error) {
+	var family string
+	err := db.QueryRow(
+		"UPDATE refresh_tokens SET used_at = $1 WHERE jti = $2 AND used_at IS NULL AND revoked_at IS NULL RETURNING family",
+		time.Now().UTC(), jti,
+	).Scan(&family)
+	return family, err
+}
+
+func GetRefreshToken(db *sql.DB, jti string) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	var usedAt sql.NullTime
+	var isRevokedInt int
+	err := db.QueryRow(
+		"SELECT jti, user_id, family, used_at, revoked_at IS NOT NULL FROM refresh_tokens WHERE jti = $1",
+		jti,
+	).Scan(&token.Jti, &token.UserId, &token.Family, &usedAt, &isRevokedInt)
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	token.IsRevoked = isRevokedInt != 0
+	return token, nil
+}
+
+func RevokeRefreshTokenFamily(db *sql.DB, family string) error {
+	_, err := db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE family = $2 AND revoked_at IS NULL",
+		time.Now().UTC(), family,
+	)
+	return err
+}
+
+func RevokeAllRefreshTokens(db *sql.DB, userId int) error {
+	_, err := db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL",
+		time.Now().UTC(), userId,
+	)
+	return err
+}
+
+// GetLoginLockedUntil returns the latest lockout of any of the keys, which may also lie in the past
+func GetLoginLockedUntil(db *sql.DB, keys ...string) (*time.Time, error) {
+	var lockedUntil sql.NullTime
+	for _, key := range keys {
+		var current sql.NullTime
+		err := db.QueryRow("SELECT locked_until FROM login_attempts WHERE key = $1", key).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if current.Valid && (!lockedUntil.Valid || current.Time.After(lockedUntil.Time)) {
+			lockedUntil = current
+		}
+	}
+
+	if !lockedUntil.Valid {
+		return nil, nil
+	}
+	return &lockedUntil.Time, nil
+}
+
+// RecordLoginFailure increments the failures of the key and returns them, where failures before resetBefore are
+// forgotten and counting starts anew
+func RecordLoginFailure(db *sql.DB, key string, now, resetBefore time.Time) (int, error) {
+	var failures int
+	err := db.QueryRow(`
+	INSERT INTO login_attempts (key, failures, last_failure) VALUES ($1, 1, $2)
+	ON CONFLICT (key) DO UPDATE SET
+		failures = CASE WHEN last_failure < $3 THEN 1 ELSE failures + 1 END,
+		last_failure = $2
+	RETURNING failures
+	`, key, now.UTC(), resetBefore.UTC()).Scan(&failures)
+	return failures, err
+}
+
+func LockLogin(db *sql.DB, key string, lockedUntil time.Time) error {
+	_, err := db.Exec("UPDATE login_attempts SET locked_until = $1 WHERE key = $2", lockedUntil.UTC(), key)
+	return err
+}
+
+func DeleteLoginAttempts(db *sql.DB, key string) error {
+	_, err := db.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+const selectBranchColumns = "SELECT b.id, b.name, b.root_node, b.is_public FROM branches b"
+
+func scanBranch(row interface{ Scan(...any) error }) (*Branch, error) {
+	branch := &Branch{}
+	var isPublicInt int
+	if err := row.Scan(&branch.Id, &branch.Name, &branch.RootNodeId, &isPublicInt); err != nil {
+		return nil, err
+	}
+	branch.IsPublic = isPublicInt != 0
+	return branch, nil
+}
+
+func SelectAllBranches(db *sql.DB) ([]*Branch, error) {
+	return selectBranches(db, selectBranchColumns+" ORDER BY b.name")
+}
+
+func SelectBranchesOfUser(db *sql.DB, userId int) ([]*Branch, error) {
+	return selectBranches(db, selectBranchColumns+`
+	JOIN user_branches ub ON ub.branch_id = b.id
+	WHERE ub.user_id = $1
+	ORDER BY b.name
+	`, userId)
+}
+
+func SelectPublicBranches(db *sql.DB) ([]*Branch, error) {
+	return selectBranches(db, selectBranchColumns+" WHERE b.is_public = 1 ORDER BY b.name")
+}
+
+func selectBranches(db *sql.DB, query string, args ...any) ([]*Branch, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	branches := make([]*Branch, 0)
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+
+	return branches, rows.Err()
+}
+
+func GetBranchById(db *sql.DB, id int) (*Branch, error) {
+	return scanBranch(db.QueryRow(selectBranchColumns+" WHERE b.id = $1", id))
+}
+
+func GetBranchByName(db *sql.DB, name string) (*Branch, error) {
+	return scanBranch(db.QueryRow(selectBranchColumns+" WHERE b.name = $1", name))
+}
+
+func InsertBranch(db *sql.DB, name, rootNodeId string) (*Branch, error) {
+	res, err := db.Exec("INSERT INTO branches (name, root_node) VALUES ($1, $2)", name, rootNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetBranchById(db, int(lastID))
+}
+
+func UpdateBranchPublic(db *sql.DB, id int, isPublic bool) error {
+	_, err := db.Exec("UPDATE branches SET is_public = $1 WHERE id = $2", boolToInt(isPublic), id)
+	return err
+}
+
+func DeleteBranch(db *sql.DB, id int) error {
+	if _, err := db.Exec("DELETE FROM user_branches WHERE branch_id = $1", id); err != nil {
+		return err
+	}
+	_, err := db.Exec("DELETE FROM branches WHERE id = $1", id)
+	return err
+}
+
+func InsertUserBranch(db *sql.DB, userId, branchId int) error {
+	_, err := db.Exec(
+		"INSERT INTO user_branches (user_id, branch_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userId, branchId,
+	)
+	return err
+}
+
+func DeleteUserBranch(db *sql.DB, userId, branchId int) error {
+	_, err := db.Exec("DELETE FROM user_branches WHERE user_id = $1 AND branch_id = $2", userId, branchId)
+	return err
+}
+
+func SelectPublicPersons(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT node FROM public_persons ORDER BY node")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodeIds := make([]string, 0)
+	for rows.Next() {
+		var nodeId string
+		if err := rows.Scan(&nodeId); err != nil {
+			return nil, err
+		}
+		nodeIds = append(nodeIds, nodeId)
+	}
+
+	return nodeIds, rows.Err()
+}
+
+func InsertPublicPerson(db *sql.DB, nodeId string) error {
+	_, err := db.Exec("INSERT INTO public_persons (node) VALUES ($1) ON CONFLICT DO NOTHING", nodeId)
+	return err
+}
+
+func DeletePublicPerson(db *sql.DB, nodeId string) error {
+	_, err := db.Exec("DELETE FROM public_persons WHERE node = $1", nodeId)
+	return err
+}
+
+func InsertAuditEntry(db *sql.DB, entry *AuditEntry) error {
+	_, err := db.Exec(`
+	INSERT INTO audit_log (timestamp, username, action, entity_type, entity_id, before, after)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entry.Timestamp.UTC(), entry.Username, entry.Action, entry.EntityType, entry.EntityId,
+		nullableText(entry.Before), nullableText(entry.After),
+	)
+	return err
+}
+
+func SelectAuditEntries(db *sql.DB, filter AuditFilter) ([]*AuditEntry, error) {
+	query := "SELECT id, timestamp, username, action, entity_type, entity_id, before, after FROM audit_log WHERE 1 = 1"
+	args := make([]any, 0)
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND %s $%d", condition, len(args))
+	}
+	if filter.Username != "" {
+		addCondition("username =", filter.Username)
+	}
+	if filter.EntityType != "" {
+		addCondition("entity_type =", filter.EntityType)
+	}
+	if filter.EntityId != "" {
+		addCondition("entity_id =", filter.EntityId)
+	}
+	if filter.From != nil {
+		addCondition("timestamp >=", filter.From.UTC())
+	}
+	if filter.To != nil {
+		addCondition("timestamp <", filter.To.UTC())
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY timestamp DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		entry := &AuditEntry{}
+		var before, after sql.NullString
+		err := rows.Scan(&entry.Id, &entry.Timestamp, &entry.Username, &entry.Action, &entry.EntityType,
+			&entry.EntityId, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			entry.Before = []byte(before.String)
+		}
+		if after.Valid {
+			entry.After = []byte(after.String)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func nullableText(data []byte) any {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
+
+// InsertRevision numbers the revision consecutively within its entity
+func InsertRevision(db *sql.DB, revision *Revision) error {
+	_, err := db.Exec(`
+	INSERT INTO revisions (entity_type, entity_id, version, timestamp, username, data)
+	SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5
+	FROM revisions WHERE entity_type = $1 AND entity_id = $2
+	`, revision.EntityType, revision.EntityId, revision.Timestamp.UTC(), revision.Username, nullableText(revision.Data))
+	return err
+}
+
+func HasRevisions(db *sql.DB, entityType, entityId string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM revisions WHERE entity_type = $1 AND entity_id = $2)",
+		entityType, entityId,
+	).Scan(&exists)
+	return exists, err
+}
+
+const selectRevisions = "SELECT id, entity_type, entity_id, version, timestamp, username, data FROM revisions"
+
+func scanRevision(row interface{ Scan(...any) error }) (*Revision, error) {
+	revision := &Revision{}
+	var data sql.NullString
+	err := row.Scan(&revision.Id, &revision.EntityType, &revision.EntityId, &revision.Version, &revision.Timestamp,
+		&revision.Username, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Valid {
+		revision.Data = []byte(data.String)
+	}
+	return revision, nil
+}
+
+func GetRevisionById(db *sql.DB, id int) (*Revision, error) {
+	return scanRevision(db.QueryRow(selectRevisions+" WHERE id = $1", id))
+}
+
+// SelectRevisionsOfPerson returns the newest revisions first, including those of all relations whose ids contain
+// the person's id
+func SelectRevisionsOfPerson(db *sql.DB, personType, personId string) ([]*Revision, error) {
+	query := selectRevisions + `
+	WHERE (entity_type = $1 AND entity_id = $2)
+	OR (entity_type <> $1 AND (entity_id LIKE $2 || '/%' OR entity_id LIKE '%/' || $2))
+	ORDER BY timestamp DESC, id DESC
+	`
+	args := []any{personType, personId}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]*Revision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
 }
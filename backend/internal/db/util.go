@@ -1,14 +1,30 @@
 package db
 
-import "github.com/kuzudb/go-kuzu"
+func executeQuery[R any](pool *KuzuPool, query string, mapper func(map[string]any) R) ([]R, error) {
+	return executePreparedStatement(pool, query, make(map[string]any), mapper)
+}
+
+func executeQuerySingle[R any](pool *KuzuPool, query string, mapper func(map[string]any) R) (R, error) {
+	var null R
+	result, err := executeQuery(pool, query, mapper)
+	if err != nil {
+		return null, err
+	}
+
+	if len(result) == 0 {
+		return null, nil
+	}
 
-func executeQuery[R any](conn *kuzu.Connection, query string, mapper func(map[string]any) R) ([]R, error) {
-	return executePreparedStatement(conn, query, make(map[string]any), mapper)
+	return result[0], nil
+}
+
+func executePreparedStatement[R any](pool *KuzuPool, query string, args map[string]any, mapper func(map[string]any) R) ([]R, error) {
+	return execute(pool, false, query, args, mapper)
 }
 
-func executeQuerySingle[R any](conn *kuzu.Connection, query string, mapper func(map[string]any) R) (R, error) {
+func executePreparedStatementSingle[R any](pool *KuzuPool, query string, args map[string]any, mapper func(map[string]any) R) (R, error) {
 	var null R
-	result, err := executeQuery(conn, query, mapper)
+	result, err := executePreparedStatement(pool, query, args, mapper)
 	if err != nil {
 		return null, err
 	}
@@ -20,7 +36,51 @@ func executeQuerySingle[R any](conn *kuzu.Connection, query string, mapper func(
 	return result[0], nil
 }
 
-func executePreparedStatement[R any](conn *kuzu.Connection, query string, args map[string]any, mapper func(map[string]any) R) ([]R, error) {
+// executeWriteStatementSingle is meant for write statements returning the written entity
+func executeWriteStatementSingle[R any](pool *KuzuPool, query string, args map[string]any, mapper func(map[string]any) R) (R, error) {
+	var null R
+	result, err := execute(pool, true, query, args, mapper)
+	if err != nil {
+		return null, err
+	}
+
+	if len(result) == 0 {
+		return null, nil
+	}
+
+	return result[0], nil
+}
+
+// executeStatement is meant for write statements where the result itself is of no interest
+func executeStatement(pool *KuzuPool, query string, args map[string]any) error {
+	_, err := execute(pool, true, query, args, func(map[string]any) any { return nil })
+	return err
+}
+
+// transaction is KuzuPool.Transaction for statements which return a result
+func transaction[R any](pool *KuzuPool, fn func(tx *KuzuPool) (R, error)) (R, error) {
+	var result R
+	err := pool.Transaction(func(tx *KuzuPool) error {
+		var err error
+		result, err = fn(tx)
+		return err
+	})
+	return result, err
+}
+
+// execute runs the statement on a connection borrowed from the pool, where writes wait for each other. Within a
+// transaction, it runs on the connection of the transaction instead.
+func execute[R any](pool *KuzuPool, write bool, query string, args map[string]any, mapper func(map[string]any) R) ([]R, error) {
+	conn := pool.tx
+	if conn == nil {
+		var err error
+		conn, err = pool.acquire(write)
+		if err != nil {
+			return nil, err
+		}
+		defer pool.release(conn, write)
+	}
+
 	ps, err := conn.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -50,3 +110,11 @@ func executePreparedStatement[R any](conn *kuzu.Connection, query string, args m
 
 	return items, nil
 }
+
+// nullable dereferences optional values, as kuzu can only bind untyped nil as NULL
+func nullable[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type HttpError struct {
 	Code    int
@@ -81,6 +84,18 @@ func NewUnprocessableEntityError(msg string) *UnprocessableEntityError {
 	return &UnprocessableEntityError{HttpError: &HttpError{Code: 422, Message: msg}}
 }
 
+type TooManyRequestsError struct {
+	*HttpError
+	RetryAfter time.Duration
+}
+
+func NewTooManyRequestsError(msg string, retryAfter time.Duration) *TooManyRequestsError {
+	if len(msg) == 0 {
+		msg = "Too Many Requests"
+	}
+	return &TooManyRequestsError{HttpError: &HttpError{Code: 429, Message: msg}, RetryAfter: retryAfter}
+}
+
 type InternalServerError struct {
 	*HttpError
 }
@@ -1,6 +1,10 @@
 package errors
 
-import "net/http"
+import (
+	"math"
+	"net/http"
+	"strconv"
+)
 
 func HandleHttpError(w http.ResponseWriter, r *http.Request, err error) {
 	switch e := err.(type) {
@@ -16,6 +20,9 @@ func HandleHttpError(w http.ResponseWriter, r *http.Request, err error) {
 		http.Error(w, e.Error(), http.StatusConflict)
 	case *UnprocessableEntityError:
 		http.Error(w, e.Error(), http.StatusUnprocessableEntity)
+	case *TooManyRequestsError:
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+		http.Error(w, e.Error(), http.StatusTooManyRequests)
 	case *InternalServerError:
 		http.Error(w, e.Error(), http.StatusInternalServerError)
 	case *NotImplementedError:
@@ -0,0 +1,188 @@
+package gedcom
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+)
+
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+var qualifiers = map[string]string{
+	"ABT": dates.QUALIFIER_ABOUT, "CAL": dates.QUALIFIER_ABOUT, "EST": dates.QUALIFIER_ABOUT,
+	"BEF": dates.QUALIFIER_BEFORE, "TO": dates.QUALIFIER_BEFORE,
+	"AFT": dates.QUALIFIER_AFTER, "FROM": dates.QUALIFIER_AFTER,
+	"BET": dates.QUALIFIER_BETWEEN,
+}
+
+// ParseDate converts a GEDCOM date value into a date. Qualifiers like ABT or BEF and ranges are kept, where periods
+// are treated like ranges, i.e. "FROM 1800 TO 1810" like "BET 1800 AND 1810". Dates that cannot be represented, e.g.
+// pure phrases or non-Gregorian/Julian calendars, result in an error.
+func ParseDate(value string) (*dates.Date, error) {
+	fields := strings.Fields(strings.ToUpper(value))
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "(") {
+		return nil, fmt.Errorf("date '%s' has no date value", value)
+	}
+
+	date := &dates.Date{}
+	if qualifier, ok := qualifiers[fields[0]]; ok {
+		date.Qualifier = &qualifier
+		fields = fields[1:]
+	} else if fields[0] == "INT" {
+		fields = fields[1:]
+	}
+	// Interpreted dates are followed by the original phrase
+	for i, field := range fields {
+		if strings.HasPrefix(field, "(") {
+			fields = fields[:i]
+			break
+		}
+	}
+
+	var toFields []string
+	for i, field := range fields {
+		if field == "AND" || field == "TO" {
+			fields, toFields = fields[:i], fields[i+1:]
+			between := dates.QUALIFIER_BETWEEN
+			date.Qualifier = &between
+			break
+		}
+	}
+	if date.Qualifier != nil && *date.Qualifier == dates.QUALIFIER_BETWEEN && len(toFields) == 0 {
+		return nil, fmt.Errorf("date '%s' has no end of its range", value)
+	}
+
+	var err error
+	if date.Calendar, date.Year, date.Month, date.Day, err = parseDateValue(fields); err != nil {
+		return nil, fmt.Errorf("date '%s' %w", value, err)
+	}
+	if len(toFields) > 0 {
+		var toCalendar *string
+		if toCalendar, date.ToYear, date.ToMonth, date.ToDay, err = parseDateValue(toFields); err != nil {
+			return nil, fmt.Errorf("date '%s' %w", value, err)
+		}
+		if derefCalendar(toCalendar) != derefCalendar(date.Calendar) {
+			return nil, fmt.Errorf("date '%s' mixes calendars", value)
+		}
+	}
+
+	return date, nil
+}
+
+// parseDateValue parses a single date with an optional calendar escape, e.g. "@#DJULIAN@ 12 MAR 1750"
+func parseDateValue(fields []string) (calendar *string, year, month, day *int32, err error) {
+	if len(fields) > 0 {
+		switch strings.Trim(fields[0], "@#") {
+		case "DGREGORIAN", "GREGORIAN":
+			fields = fields[1:]
+		case "DJULIAN", "JULIAN":
+			julian := dates.CALENDAR_JULIAN
+			calendar = &julian
+			fields = fields[1:]
+		case "DFRENCH", "FRENCH_R", "DHEBREW", "HEBREW", "DROMAN", "DUNKNOWN":
+			return nil, nil, nil, nil, fmt.Errorf("uses an unsupported calendar")
+		}
+	}
+	if len(fields) > 0 && (fields[len(fields)-1] == "B.C." || fields[len(fields)-1] == "BCE") {
+		return nil, nil, nil, nil, fmt.Errorf("is before the common era")
+	}
+
+	switch len(fields) {
+	case 3:
+		value, err := parseDatePart(fields[0], 1, 31)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("has invalid day: %w", err)
+		}
+		day = &value
+		fields = fields[1:]
+		fallthrough
+	case 2:
+		value := int32(slices.Index(months, fields[0]) + 1)
+		if value == 0 {
+			return nil, nil, nil, nil, fmt.Errorf("has invalid month '%s'", fields[0])
+		}
+		month = &value
+		fields = fields[1:]
+		fallthrough
+	case 1:
+		// Dual years like 1750/51 are reduced to the first year
+		yearStr, _, _ := strings.Cut(fields[0], "/")
+		value, err := parseDatePart(yearStr, 1, 9999)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("has invalid year: %w", err)
+		}
+		year = &value
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("cannot be parsed")
+	}
+
+	return calendar, year, month, day, nil
+}
+
+// FormatDate converts a partial date into a GEDCOM date value, which is empty if the year is unknown
+func FormatDate(year, month, day *int32) string {
+	if year == nil {
+		return ""
+	}
+	parts := make([]string, 0, 3)
+	if month != nil && *month >= 1 && *month <= 12 {
+		if day != nil {
+			parts = append(parts, strconv.Itoa(int(*day)))
+		}
+		parts = append(parts, months[*month-1])
+	}
+	parts = append(parts, strconv.Itoa(int(*year)))
+	return strings.Join(parts, " ")
+}
+
+// FormatQualifiedDate converts a date into a GEDCOM date value including its qualifier and calendar, which is empty
+// if the year is unknown
+func FormatQualifiedDate(date dates.Date) string {
+	value := formatCalendarDate(date.Calendar, date.Year, date.Month, date.Day)
+	if len(value) == 0 || date.Qualifier == nil {
+		return value
+	}
+
+	switch *date.Qualifier {
+	case dates.QUALIFIER_ABOUT:
+		return "ABT " + value
+	case dates.QUALIFIER_BEFORE:
+		return "BEF " + value
+	case dates.QUALIFIER_AFTER:
+		return "AFT " + value
+	case dates.QUALIFIER_BETWEEN:
+		if toValue := formatCalendarDate(date.Calendar, date.ToYear, date.ToMonth, date.ToDay); len(toValue) > 0 {
+			return "BET " + value + " AND " + toValue
+		}
+	}
+	return value
+}
+
+func formatCalendarDate(calendar *string, year, month, day *int32) string {
+	value := FormatDate(year, month, day)
+	if len(value) > 0 && derefCalendar(calendar) == dates.CALENDAR_JULIAN {
+		return "@#DJULIAN@ " + value
+	}
+	return value
+}
+
+func derefCalendar(calendar *string) string {
+	if calendar == nil {
+		return dates.CALENDAR_GREGORIAN
+	}
+	return *calendar
+}
+
+func parseDatePart(value string, min, max int) (int32, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if number < min || number > max {
+		return 0, fmt.Errorf("%d is out of range", number)
+	}
+	return int32(number), nil
+}
@@ -0,0 +1,242 @@
+package gedcom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+const submitterXref = "@SUBM@"
+
+type family struct {
+	xref     string
+	partners []uuid.UUID
+	children []uuid.UUID
+	marriage *db.MarriageRelation
+}
+
+// familyKey identifies a family by its (up to two) partners independent of their order
+type familyKey struct {
+	partner1 uuid.UUID
+	partner2 uuid.UUID
+}
+
+func newFamilyKey(partners ...uuid.UUID) familyKey {
+	sorted := slices.Clone(partners)
+	slices.SortFunc(sorted, func(a, b uuid.UUID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	key := familyKey{}
+	if len(sorted) > 0 {
+		key.partner1 = sorted[0]
+	}
+	if len(sorted) > 1 {
+		key.partner2 = sorted[1]
+	}
+	return key
+}
+
+// Write serializes the persons as a GEDCOM 5.5.1 file in the given order. Marriages and shared parents become FAM
+// records, while relations to persons outside the given set are dropped.
+func Write(writer io.Writer, persons []*db.Person, parentRelations []*db.ParentRelation, marriageRelations []*db.MarriageRelation) error {
+	personXrefs := make(map[uuid.UUID]string, len(persons))
+	personsById := make(map[uuid.UUID]*db.Person, len(persons))
+	for i, person := range persons {
+		personXrefs[person.Id] = fmt.Sprintf("@I%d@", i+1)
+		personsById[person.Id] = person
+	}
+
+	families := make([]*family, 0)
+	familiesByKey := make(map[familyKey]*family)
+	getFamily := func(partners ...uuid.UUID) *family {
+		key := newFamilyKey(partners...)
+		if f, ok := familiesByKey[key]; ok {
+			return f
+		}
+		f := &family{xref: fmt.Sprintf("@F%d@", len(families)+1), partners: partners}
+		families = append(families, f)
+		familiesByKey[key] = f
+		return f
+	}
+
+	for _, relation := range marriageRelations {
+		if _, ok := personXrefs[relation.Person1Id]; !ok {
+			continue
+		}
+		if _, ok := personXrefs[relation.Person2Id]; !ok {
+			continue
+		}
+		getFamily(relation.Person1Id, relation.Person2Id).marriage = relation
+	}
+
+	parentsByChild := make(map[uuid.UUID][]uuid.UUID)
+	children := make([]uuid.UUID, 0)
+	for _, relation := range parentRelations {
+		if _, ok := personXrefs[relation.ParentId]; !ok {
+			continue
+		}
+		if _, ok := personXrefs[relation.ChildId]; !ok {
+			continue
+		}
+		if _, ok := parentsByChild[relation.ChildId]; !ok {
+			children = append(children, relation.ChildId)
+		}
+		parentsByChild[relation.ChildId] = append(parentsByChild[relation.ChildId], relation.ParentId)
+	}
+	for _, childId := range children {
+		f := getFamily(parentsByChild[childId]...)
+		f.children = append(f.children, childId)
+	}
+
+	familiesAsPartner := make(map[uuid.UUID][]string)
+	familiesAsChild := make(map[uuid.UUID][]string)
+	for _, f := range families {
+		for _, partner := range f.partners {
+			familiesAsPartner[partner] = append(familiesAsPartner[partner], f.xref)
+		}
+		for _, child := range f.children {
+			familiesAsChild[child] = append(familiesAsChild[child], f.xref)
+		}
+	}
+
+	w := bufio.NewWriter(writer)
+	line := func(level int, tag, value string) {
+		if len(value) == 0 {
+			fmt.Fprintf(w, "%d %s\n", level, tag)
+		} else {
+			fmt.Fprintf(w, "%d %s %s\n", level, tag, strings.ReplaceAll(value, "@", "@@"))
+		}
+	}
+	// Dates are written unescaped, as calendar escapes like @#DJULIAN@ must not be doubled
+	dateLine := func(level int, value string) {
+		fmt.Fprintf(w, "%d DATE %s\n", level, value)
+	}
+	pointer := func(level int, tag, xref string) {
+		fmt.Fprintf(w, "%d %s %s\n", level, tag, xref)
+	}
+	record := func(xref, tag string) {
+		fmt.Fprintf(w, "0 %s %s\n", xref, tag)
+	}
+
+	line(0, "HEAD", "")
+	line(1, "SOUR", "FAMILY_TREE_APP")
+	line(2, "NAME", "Family Tree App")
+	line(1, "GEDC", "")
+	line(2, "VERS", "5.5.1")
+	line(2, "FORM", "LINEAGE-LINKED")
+	line(1, "CHAR", "UTF-8")
+	pointer(1, "SUBM", submitterXref)
+
+	record(submitterXref, "SUBM")
+	line(1, "NAME", "Family Tree App")
+
+	for _, person := range persons {
+		record(personXrefs[person.Id], "INDI")
+		writeName(line, person)
+
+		switch deref(person.Gender) {
+		case "m":
+			line(1, "SEX", "M")
+		case "f":
+			line(1, "SEX", "F")
+		default:
+			line(1, "SEX", "U")
+		}
+
+		if birthDate := FormatQualifiedDate(person.BirthDate()); len(birthDate) > 0 {
+			line(1, "BIRT", "")
+			dateLine(2, birthDate)
+		}
+		if deathDate := FormatQualifiedDate(person.DeathDate()); len(deathDate) > 0 {
+			line(1, "DEAT", "")
+			dateLine(2, deathDate)
+		} else if person.IsDead != nil && *person.IsDead {
+			line(1, "DEAT", "Y")
+		}
+
+		for _, xref := range familiesAsChild[person.Id] {
+			pointer(1, "FAMC", xref)
+		}
+		for _, xref := range familiesAsPartner[person.Id] {
+			pointer(1, "FAMS", xref)
+		}
+	}
+
+	for _, f := range families {
+		record(f.xref, "FAM")
+
+		husbands, wives := make([]uuid.UUID, 0), make([]uuid.UUID, 0)
+		for _, partner := range f.partners {
+			if deref(personsById[partner].Gender) == "f" {
+				wives = append(wives, partner)
+			} else {
+				husbands = append(husbands, partner)
+			}
+		}
+		// GEDCOM 5.5.1 only knows one HUSB and one WIFE per family
+		if len(husbands) == 2 {
+			wives, husbands = husbands[1:], husbands[:1]
+		} else if len(wives) == 2 {
+			husbands, wives = wives[:1], wives[1:]
+		}
+		for _, partner := range husbands {
+			pointer(1, "HUSB", personXrefs[partner])
+		}
+		for _, partner := range wives {
+			pointer(1, "WIFE", personXrefs[partner])
+		}
+
+		if f.marriage != nil {
+			line(1, "MARR", "")
+			if sinceDate := FormatDate(f.marriage.SinceYear, f.marriage.SinceMonth, f.marriage.SinceDay); len(sinceDate) > 0 {
+				dateLine(2, sinceDate)
+			}
+			if untilDate := FormatDate(f.marriage.UntilYear, f.marriage.UntilMonth, f.marriage.UntilDay); len(untilDate) > 0 {
+				line(1, "DIV", "")
+				dateLine(2, untilDate)
+			}
+		}
+
+		for _, child := range f.children {
+			pointer(1, "CHIL", personXrefs[child])
+		}
+	}
+
+	line(0, "TRLR", "")
+
+	return w.Flush()
+}
+
+func writeName(line func(int, string, string), person *db.Person) {
+	given := strings.TrimSpace(deref(person.FirstName) + " " + deref(person.MiddleName))
+	surname := deref(person.LastName)
+
+	line(1, "NAME", strings.TrimSpace(fmt.Sprintf("%s /%s/", given, surname)))
+	if len(given) > 0 {
+		line(2, "GIVN", given)
+	}
+	if len(surname) > 0 {
+		line(2, "SURN", surname)
+	}
+
+	if birthName := deref(person.BirthName); len(birthName) > 0 && birthName != surname {
+		line(1, "NAME", strings.TrimSpace(fmt.Sprintf("%s /%s/", given, birthName)))
+		if len(given) > 0 {
+			line(2, "GIVN", given)
+		}
+		line(2, "SURN", birthName)
+		line(2, "TYPE", "birth")
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,258 @@
+package gedcom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+const maxParentsPerChild = 2
+
+// Import is the graph representation of a GEDCOM file, where every INDI record became a new person
+type Import struct {
+	Persons           []*db.Person
+	ParentRelations   []*db.ParentRelation
+	MarriageRelations []*db.MarriageRelation
+	// Xrefs maps the newly assigned ids back to the GEDCOM cross-reference identifiers, e.g. for reporting
+	Xrefs    map[uuid.UUID]string
+	Warnings []string
+}
+
+func (imp *Import) warn(xref, format string, args ...any) {
+	imp.Warnings = append(imp.Warnings, fmt.Sprintf("%s: %s", xref, fmt.Sprintf(format, args...)))
+}
+
+// Map converts INDI records into persons and FAM records into parent and marriage relations.
+// Only biological parent relations are considered and all other records are ignored.
+func Map(records []*Record) (*Import, error) {
+	imp := &Import{
+		Persons:           make([]*db.Person, 0),
+		ParentRelations:   make([]*db.ParentRelation, 0),
+		MarriageRelations: make([]*db.MarriageRelation, 0),
+		Xrefs:             make(map[uuid.UUID]string),
+		Warnings:          make([]string, 0),
+	}
+
+	idsByXref := make(map[string]uuid.UUID)
+	nonBiological := make(map[string]bool)
+	for _, record := range records {
+		if record.Tag != "INDI" {
+			continue
+		}
+		if len(record.Xref) == 0 {
+			imp.warn("INDI", "skipped record without cross-reference identifier")
+			continue
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		person := mapPerson(imp, record)
+		person.Id = id
+
+		idsByXref[record.Xref] = id
+		imp.Xrefs[id] = record.Xref
+		imp.Persons = append(imp.Persons, person)
+
+		for _, famc := range record.All("FAMC") {
+			pedigree := strings.ToUpper(famc.FirstValue("PEDI"))
+			if len(pedigree) > 0 && pedigree != "BIRTH" {
+				nonBiological[famc.Value+record.Xref] = true
+			}
+		}
+	}
+
+	parentCount := make(map[uuid.UUID]int)
+	seenParents := make(map[db.ParentRelation]bool)
+	seenMarriages := make(map[db.MarriageKey]bool)
+	for _, record := range records {
+		if record.Tag != "FAM" {
+			continue
+		}
+
+		parentIds := make([]uuid.UUID, 0, 2)
+		for _, tag := range []string{"HUSB", "WIFE"} {
+			for _, partner := range record.All(tag) {
+				if id, ok := idsByXref[partner.Value]; ok {
+					parentIds = append(parentIds, id)
+				} else {
+					imp.warn(record.Xref, "%s references unknown individual %s", tag, partner.Value)
+				}
+			}
+		}
+
+		if len(parentIds) == 2 && !isUnmarried(record) {
+			key := db.MarriageKey{Person1Id: parentIds[0], Person2Id: parentIds[1]}
+			reverseKey := db.MarriageKey{Person1Id: parentIds[1], Person2Id: parentIds[0]}
+			if !seenMarriages[key] && !seenMarriages[reverseKey] {
+				seenMarriages[key] = true
+				imp.MarriageRelations = append(imp.MarriageRelations, mapMarriage(imp, record, parentIds[0], parentIds[1]))
+			}
+		}
+
+		for _, child := range record.All("CHIL") {
+			childId, ok := idsByXref[child.Value]
+			if !ok {
+				imp.warn(record.Xref, "CHIL references unknown individual %s", child.Value)
+				continue
+			}
+			if nonBiological[record.Xref+child.Value] {
+				imp.warn(child.Value, "skipped non-biological parents of family %s", record.Xref)
+				continue
+			}
+
+			for _, parentId := range parentIds {
+				relation := db.ParentRelation{ParentId: parentId, ChildId: childId}
+				if seenParents[relation] {
+					continue
+				}
+				if parentCount[childId] >= maxParentsPerChild {
+					imp.warn(child.Value, "skipped parent %s, as there are already %d parents", imp.Xrefs[parentId], maxParentsPerChild)
+					continue
+				}
+				seenParents[relation] = true
+				parentCount[childId]++
+				imp.ParentRelations = append(imp.ParentRelations, &relation)
+			}
+		}
+	}
+
+	return imp, nil
+}
+
+func mapPerson(imp *Import, record *Record) *db.Person {
+	person := &db.Person{}
+
+	// The first name is the primary one, while typed names may override the last name or the birth name
+	for i, name := range record.All("NAME") {
+		given, surname := splitName(name)
+		switch strings.ToUpper(name.FirstValue("TYPE")) {
+		case "BIRTH", "MAIDEN":
+			person.BirthName = surname
+		case "MARRIED":
+			person.LastName = surname
+		default:
+			if i > 0 {
+				continue
+			}
+			if len(given) > 0 {
+				person.FirstName = &given[0]
+				if len(given) > 1 {
+					middleName := strings.Join(given[1:], " ")
+					person.MiddleName = &middleName
+				}
+			}
+			if person.LastName == nil {
+				person.LastName = surname
+			}
+			if person.BirthName == nil {
+				person.BirthName = surname
+			}
+		}
+	}
+
+	switch strings.ToUpper(record.FirstValue("SEX")) {
+	case "M":
+		gender := "m"
+		person.Gender = &gender
+	case "F":
+		gender := "f"
+		person.Gender = &gender
+	}
+
+	if birth := record.First("BIRT"); birth != nil {
+		if date := mapDate(imp, record.Xref, birth); date != nil {
+			person.SetBirthDate(*date)
+		}
+	}
+	if death := record.First("DEAT"); death != nil {
+		isDead := true
+		person.IsDead = &isDead
+		if date := mapDate(imp, record.Xref, death); date != nil {
+			person.SetDeathDate(*date)
+		}
+	}
+
+	return person
+}
+
+func mapMarriage(imp *Import, record *Record, person1Id, person2Id uuid.UUID) *db.MarriageRelation {
+	relation := &db.MarriageRelation{Person1Id: person1Id, Person2Id: person2Id}
+
+	if marriage := record.First("MARR"); marriage != nil {
+		if date := mapExactDate(imp, record.Xref, marriage); date != nil {
+			relation.SinceYear, relation.SinceMonth, relation.SinceDay = date.Year, date.Month, date.Day
+		}
+	}
+	if divorce := record.First("DIV"); divorce != nil {
+		if date := mapExactDate(imp, record.Xref, divorce); date != nil {
+			relation.UntilYear, relation.UntilMonth, relation.UntilDay = date.Year, date.Month, date.Day
+		}
+	}
+
+	return relation
+}
+
+func mapDate(imp *Import, xref string, event *Record) *dates.Date {
+	value := event.FirstValue("DATE")
+	if len(value) == 0 {
+		return nil
+	}
+
+	date, err := ParseDate(value)
+	if err != nil {
+		imp.warn(xref, "ignored %s date: %s", event.Tag, err.Error())
+		return nil
+	}
+
+	return date
+}
+
+// mapExactDate is used for dates which cannot be uncertain in the graph, where only the first date of a range is
+// kept and qualifiers are dropped
+func mapExactDate(imp *Import, xref string, event *Record) *dates.Date {
+	date := mapDate(imp, xref, event)
+	if date != nil && (date.Qualifier != nil || date.Calendar != nil) {
+		imp.warn(xref, "approximated %s date '%s'", event.Tag, event.FirstValue("DATE"))
+	}
+	return date
+}
+
+// isUnmarried detects the GEDCOM 7.0 "NO MARR" assertion, as a family is assumed to be a married couple otherwise
+func isUnmarried(family *Record) bool {
+	for _, no := range family.All("NO") {
+		if strings.ToUpper(no.Value) == "MARR" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitName returns the given names and the surname of a NAME record, preferring the GIVN and SURN substructures
+func splitName(name *Record) ([]string, *string) {
+	givenPart, surnamePart := name.Value, ""
+	if start := strings.Index(name.Value, "/"); start >= 0 {
+		givenPart = name.Value[:start]
+		surnamePart = name.Value[start+1:]
+		if end := strings.Index(surnamePart, "/"); end >= 0 {
+			surnamePart = surnamePart[:end]
+		}
+	}
+	if givn := name.FirstValue("GIVN"); len(givn) > 0 {
+		givenPart = givn
+	}
+	if surn := name.FirstValue("SURN"); len(surn) > 0 {
+		surnamePart = surn
+	}
+
+	given := strings.Fields(strings.ReplaceAll(givenPart, ",", " "))
+	surnamePart = strings.TrimSpace(surnamePart)
+	if len(surnamePart) == 0 {
+		return given, nil
+	}
+	return given, &surnamePart
+}
@@ -0,0 +1,126 @@
+package gedcom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record is a single GEDCOM line together with all of its subordinate lines
+type Record struct {
+	Level    int
+	Xref     string
+	Tag      string
+	Value    string
+	Children []*Record
+}
+
+// First returns the first direct child with the given tag or nil
+func (r *Record) First(tag string) *Record {
+	for _, child := range r.Children {
+		if child.Tag == tag {
+			return child
+		}
+	}
+	return nil
+}
+
+// All returns all direct children with the given tag
+func (r *Record) All(tag string) []*Record {
+	records := make([]*Record, 0)
+	for _, child := range r.Children {
+		if child.Tag == tag {
+			records = append(records, child)
+		}
+	}
+	return records
+}
+
+// FirstValue returns the value of the first direct child with the given tag or an empty string
+func (r *Record) FirstValue(tag string) string {
+	if child := r.First(tag); child != nil {
+		return child.Value
+	}
+	return ""
+}
+
+// Parse reads a GEDCOM 5.5.1 or 7.0 file into its top-level records. CONC and CONT lines are merged into the value
+// of their parent. Only UTF-8 (and thus ASCII) encoded files are supported.
+func Parse(reader io.Reader) ([]*Record, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	records := make([]*Record, 0)
+	stack := make([]*Record, 0)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		line = strings.TrimLeft(line, " \t")
+		if len(line) == 0 {
+			continue
+		}
+
+		record, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if record.Level > len(stack) {
+			return nil, fmt.Errorf("line %d: level %d skips a level", lineNumber, record.Level)
+		}
+		stack = stack[:record.Level]
+
+		if record.Level == 0 {
+			records = append(records, record)
+			stack = append(stack, record)
+			continue
+		}
+
+		parent := stack[len(stack)-1]
+		switch record.Tag {
+		case "CONC":
+			parent.Value += record.Value
+		case "CONT":
+			parent.Value += "\n" + record.Value
+		default:
+			parent.Children = append(parent.Children, record)
+			stack = append(stack, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func parseLine(line string) (*Record, error) {
+	levelStr, rest, _ := strings.Cut(line, " ")
+	level, err := strconv.Atoi(levelStr)
+	if err != nil || level < 0 {
+		return nil, fmt.Errorf("invalid level '%s'", levelStr)
+	}
+
+	record := &Record{Level: level}
+	if strings.HasPrefix(rest, "@") {
+		xref, remainder, _ := strings.Cut(rest, " ")
+		record.Xref = xref
+		rest = remainder
+	}
+
+	tag, value, _ := strings.Cut(rest, " ")
+	if len(tag) == 0 {
+		return nil, fmt.Errorf("missing tag")
+	}
+	record.Tag = strings.ToUpper(tag)
+	// A leading @@ escapes a literal @ in GEDCOM 7.0 and has to be doubled everywhere in GEDCOM 5.5.1
+	record.Value = strings.ReplaceAll(value, "@@", "@")
+
+	return record, nil
+}
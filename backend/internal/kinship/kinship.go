@@ -0,0 +1,362 @@
+// Package kinship names the relationship described by a path through the family graph,
+// e.g. "second cousin once removed", in every language supported by the frontend.
+package kinship
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+)
+
+const (
+	LANGUAGE_EN = "en"
+	LANGUAGE_DE = "de"
+)
+
+// term is a single kinship term. German terms keep their suffix separate from the noun, as the noun has to be
+// declined in possessive chains, e.g. "Cousin 2. Grades" becomes "des Cousins 2. Grades".
+type term struct {
+	en       string
+	de       string
+	deGenus  byte
+	deSuffix string
+}
+
+// link is one segment of a path, i.e. either a blood relation or a marriage
+type link struct {
+	ups      int
+	downs    int
+	isSpouse bool
+	gender   *string
+}
+
+// Describe names the relation of the last person of the path to the first one. Blood relations are named via their
+// closest common ancestor, while paths through marriages result in in-law terms or possessive chains like
+// "wife's first cousin". isHalf marks a pure sibling relation as half-sibling relation.
+func Describe(path *db.RelationshipPath, isHalf bool) map[string]string {
+	links := toLinks(path)
+
+	var t term
+	if len(links) == 0 {
+		t = term{en: "self", de: "Selbst", deGenus: 'n'}
+	} else if collapsed, ok := collapseInLaws(links); ok {
+		t = collapsed
+	} else if len(links) == 1 {
+		t = bloodTerm(links[0], isHalf)
+	} else {
+		t = chainTerms(links)
+	}
+
+	return map[string]string{
+		LANGUAGE_EN: t.en,
+		LANGUAGE_DE: t.de + t.deSuffix,
+	}
+}
+
+func toLinks(path *db.RelationshipPath) []link {
+	links := make([]link, 0)
+	var current *link
+	for i, step := range path.Steps {
+		gender := path.Persons[i+1].Gender
+		switch step {
+		case db.PATH_STEP_SPOUSE:
+			current = nil
+			links = append(links, link{isSpouse: true, gender: gender})
+			continue
+		case db.PATH_STEP_PARENT:
+			// Going up again after going down starts a new blood relation, e.g. the other parent of a child
+			if current == nil || current.downs > 0 {
+				links = append(links, link{})
+				current = &links[len(links)-1]
+			}
+			current.ups++
+		case db.PATH_STEP_CHILD:
+			if current == nil {
+				links = append(links, link{})
+				current = &links[len(links)-1]
+			}
+			current.downs++
+		}
+		current.gender = gender
+	}
+	return links
+}
+
+func collapseInLaws(links []link) (term, bool) {
+	first, last := links[0], links[len(links)-1]
+
+	switch {
+	case len(links) == 1 && first.isSpouse:
+		return spouseTerm(first.gender), true
+	case len(links) == 2 && first.isSpouse && last.downs == 0 && last.ups > 0:
+		return inLaw(bloodTerm(last, false)), true
+	case len(links) == 2 && first.isSpouse && last.ups == 0 && last.downs == 1:
+		return step(bloodTerm(last, false)), true
+	case len(links) == 2 && last.isSpouse && first.ups == 0 && first.downs > 0:
+		return inLaw(bloodTerm(link{downs: first.downs, gender: last.gender}, false)), true
+	case len(links) == 2 && last.isSpouse && first.ups == 1 && first.downs == 0:
+		return step(bloodTerm(link{ups: 1, gender: last.gender}, false)), true
+	case len(links) == 2 && first.isSpouse && last.ups == 1 && last.downs == 1,
+		len(links) == 2 && last.isSpouse && first.ups == 1 && first.downs == 1,
+		len(links) == 3 && first.isSpouse && last.isSpouse && links[1].ups == 1 && links[1].downs == 1:
+		return siblingInLawTerm(last.gender), true
+	case len(links) == 3 && links[1].isSpouse && first.ups == 1 && first.downs == 0 && last.ups == 0 && last.downs == 1:
+		return step(siblingTerm(last.gender, false)), true
+	case len(links) == 2 && last.isSpouse && first.ups >= 2 && first.downs == 1:
+		t := bloodTerm(link{ups: first.ups, downs: 1, gender: last.gender}, false)
+		t.en += " by marriage"
+		t.deSuffix += " (angeheiratet)"
+		return t, true
+	}
+
+	return term{}, false
+}
+
+// chainTerms concatenates the terms of all links, e.g. "wife's brother's son" or "Sohn des Bruders der Ehefrau"
+func chainTerms(links []link) term {
+	terms := make([]term, len(links))
+	for i, l := range links {
+		if l.isSpouse {
+			terms[i] = spouseTerm(l.gender)
+		} else {
+			terms[i] = bloodTerm(l, false)
+		}
+	}
+
+	en := make([]string, len(terms))
+	for i, t := range terms {
+		en[i] = t.en
+	}
+
+	last := terms[len(terms)-1]
+	de := []string{last.de + last.deSuffix}
+	for i := len(terms) - 2; i >= 0; i-- {
+		de = append(de, genitive(terms[i]))
+	}
+
+	return term{en: strings.Join(en, "'s "), de: strings.Join(de, " "), deGenus: last.deGenus}
+}
+
+func genitive(t term) string {
+	if t.deGenus == 'f' {
+		return "der " + t.de + t.deSuffix
+	}
+	noun := t.de
+	if strings.HasSuffix(noun, "e") {
+		noun += "n"
+	} else if !strings.HasSuffix(noun, "s") {
+		noun += "s"
+	}
+	return "des " + noun + t.deSuffix
+}
+
+func bloodTerm(l link, isHalf bool) term {
+	switch {
+	case l.downs == 0:
+		return ancestorTerm(l.ups, l.gender)
+	case l.ups == 0:
+		return descendantTerm(l.downs, l.gender)
+	case l.ups == 1 && l.downs == 1:
+		return siblingTerm(l.gender, isHalf)
+	case l.ups == 1:
+		return niblingTerm(l.downs, l.gender)
+	case l.downs == 1:
+		return piblingTerm(l.ups, l.gender)
+	default:
+		return cousinTerm(l.ups, l.downs, l.gender)
+	}
+}
+
+func ancestorTerm(generations int, gender *string) term {
+	t := byGender(gender,
+		term{en: "father", de: "Vater", deGenus: 'm'},
+		term{en: "mother", de: "Mutter", deGenus: 'f'},
+		term{en: "parent", de: "Elternteil", deGenus: 'n'})
+	if generations == 1 {
+		return t
+	}
+	t.en = greats(generations-2) + "grand" + t.en
+	t.de = capitalize(strings.Repeat("ur", generations-2) + "groß" + strings.ToLower(t.de))
+	return t
+}
+
+func descendantTerm(generations int, gender *string) term {
+	if generations == 1 {
+		return byGender(gender,
+			term{en: "son", de: "Sohn", deGenus: 'm'},
+			term{en: "daughter", de: "Tochter", deGenus: 'f'},
+			term{en: "child", de: "Kind", deGenus: 'n'})
+	}
+	t := byGender(gender,
+		term{en: "grandson", de: "Enkel", deGenus: 'm'},
+		term{en: "granddaughter", de: "Enkelin", deGenus: 'f'},
+		term{en: "grandchild", de: "Enkelkind", deGenus: 'n'})
+	t.en = greats(generations-2) + t.en
+	t.de = capitalize(strings.Repeat("ur", generations-2) + strings.ToLower(t.de))
+	return t
+}
+
+func siblingTerm(gender *string, isHalf bool) term {
+	t := byGender(gender,
+		term{en: "brother", de: "Bruder", deGenus: 'm'},
+		term{en: "sister", de: "Schwester", deGenus: 'f'},
+		term{en: "sibling", de: "Geschwister", deGenus: 'n'})
+	if isHalf {
+		t.en = "half-" + t.en
+		t.de = "Halb" + strings.ToLower(t.de)
+	}
+	return t
+}
+
+func niblingTerm(downs int, gender *string) term {
+	if gender == nil || (*gender != "m" && *gender != "f") {
+		return eitherGender(func(g *string) term { return niblingTerm(downs, g) })
+	}
+	t := byGender(gender,
+		term{en: "nephew", de: "Neffe", deGenus: 'm'},
+		term{en: "niece", de: "Nichte", deGenus: 'f'},
+		term{})
+	if downs == 2 {
+		return t
+	}
+	t.en = greats(downs-3) + "grand" + t.en
+	t.de = capitalize(strings.Repeat("ur", downs-3) + "groß" + strings.ToLower(t.de))
+	return t
+}
+
+func piblingTerm(ups int, gender *string) term {
+	if gender == nil || (*gender != "m" && *gender != "f") {
+		return eitherGender(func(g *string) term { return piblingTerm(ups, g) })
+	}
+	t := byGender(gender,
+		term{en: "uncle", de: "Onkel", deGenus: 'm'},
+		term{en: "aunt", de: "Tante", deGenus: 'f'},
+		term{})
+	if ups == 2 {
+		return t
+	}
+	t.en = greats(ups-2) + t.en
+	t.de = capitalize(strings.Repeat("ur", ups-3) + "groß" + strings.ToLower(t.de))
+	return t
+}
+
+func cousinTerm(ups, downs int, gender *string) term {
+	t := byGender(gender,
+		term{en: "cousin", de: "Cousin", deGenus: 'm'},
+		term{en: "cousin", de: "Cousine", deGenus: 'f'},
+		term{en: "cousin", de: "Cousin/Cousine", deGenus: 'm'})
+
+	degree := min(ups, downs) - 1
+	removed := max(ups, downs) - min(ups, downs)
+
+	t.en = ordinal(degree) + " " + t.en
+	if degree > 1 {
+		t.deSuffix = fmt.Sprintf(" %d. Grades", degree)
+	}
+	switch removed {
+	case 0:
+	case 1:
+		t.en += " once removed"
+		t.deSuffix += ", um 1 Generation versetzt"
+	case 2:
+		t.en += " twice removed"
+		t.deSuffix += ", um 2 Generationen versetzt"
+	default:
+		t.en += fmt.Sprintf(" %d times removed", removed)
+		t.deSuffix += fmt.Sprintf(", um %d Generationen versetzt", removed)
+	}
+	return t
+}
+
+func spouseTerm(gender *string) term {
+	return byGender(gender,
+		term{en: "husband", de: "Ehemann", deGenus: 'm'},
+		term{en: "wife", de: "Ehefrau", deGenus: 'f'},
+		term{en: "spouse", de: "Ehepartner", deGenus: 'm'})
+}
+
+func siblingInLawTerm(gender *string) term {
+	return byGender(gender,
+		term{en: "brother-in-law", de: "Schwager", deGenus: 'm'},
+		term{en: "sister-in-law", de: "Schwägerin", deGenus: 'f'},
+		term{en: "sibling-in-law", de: "Schwager/Schwägerin", deGenus: 'm'})
+}
+
+func inLaw(t term) term {
+	t.en += "-in-law"
+	t.de = "Schwieger" + strings.ToLower(t.de)
+	return t
+}
+
+func step(t term) term {
+	t.en = "step" + t.en
+	t.de = "Stief" + strings.ToLower(t.de)
+	return t
+}
+
+func byGender(gender *string, male, female, unknown term) term {
+	if gender == nil {
+		return unknown
+	}
+	switch *gender {
+	case "m":
+		return male
+	case "f":
+		return female
+	default:
+		return unknown
+	}
+}
+
+// eitherGender joins the female and male term for persons of unknown gender, e.g. "great-aunt/great-uncle"
+func eitherGender(termFor func(gender *string) term) term {
+	male, female := "m", "f"
+	m, f := termFor(&male), termFor(&female)
+	return term{en: f.en + "/" + m.en, de: f.de + "/" + m.de, deGenus: f.deGenus, deSuffix: f.deSuffix}
+}
+
+// greats returns the prefix for the given number of additional generations,
+// i.e. "great-", "great-great-" and then "3rd great-" and so on
+func greats(n int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case n <= 2:
+		return strings.Repeat("great-", n)
+	default:
+		return ordinalNumber(n) + " great-"
+	}
+}
+
+var ordinalWords = []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+
+func ordinal(n int) string {
+	if n >= 1 && n <= len(ordinalWords) {
+		return ordinalWords[n-1]
+	}
+	return ordinalNumber(n)
+}
+
+func ordinalNumber(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+func capitalize(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	runes := []rune(s)
+	return strings.ToUpper(string(runes[0])) + string(runes[1:])
+}
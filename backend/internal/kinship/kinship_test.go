@@ -0,0 +1,97 @@
+package kinship
+
+import (
+	"testing"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+)
+
+// hop is a step of a path together with the gender of the person reached by it, where "" is unknown
+type hop struct {
+	step   db.PathStep
+	gender string
+}
+
+func up(gender string) hop     { return hop{db.PATH_STEP_PARENT, gender} }
+func down(gender string) hop   { return hop{db.PATH_STEP_CHILD, gender} }
+func spouse(gender string) hop { return hop{db.PATH_STEP_SPOUSE, gender} }
+
+func path(hops ...hop) *db.RelationshipPath {
+	p := &db.RelationshipPath{Persons: []*db.Person{{}}, Steps: make([]db.PathStep, 0, len(hops))}
+	for _, h := range hops {
+		person := &db.Person{}
+		if h.gender != "" {
+			gender := h.gender
+			person.Gender = &gender
+		}
+		p.Persons = append(p.Persons, person)
+		p.Steps = append(p.Steps, h.step)
+	}
+	return p
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   *db.RelationshipPath
+		isHalf bool
+		en     string
+		de     string
+	}{
+		{"self", path(), false, "self", "Selbst"},
+		{"father", path(up("m")), false, "father", "Vater"},
+		{"mother", path(up("f")), false, "mother", "Mutter"},
+		{"grandparent of unknown gender", path(up("m"), up("")), false, "grandparent", "Großelternteil"},
+		{"great-great-grandmother", path(up("f"), up("f"), up("f"), up("f")), false,
+			"great-great-grandmother", "Ururgroßmutter"},
+		{"3rd great-grandfather", path(up("m"), up("m"), up("m"), up("m"), up("m")), false,
+			"3rd great-grandfather", "Urururgroßvater"},
+		{"son", path(down("m")), false, "son", "Sohn"},
+		{"granddaughter", path(down("m"), down("f")), false, "granddaughter", "Enkelin"},
+		{"great-grandson", path(down("f"), down("f"), down("m")), false, "great-grandson", "Urenkel"},
+		{"brother", path(up("m"), down("m")), false, "brother", "Bruder"},
+		{"half-sister", path(up("m"), down("f")), true, "half-sister", "Halbschwester"},
+		{"nephew", path(up("m"), down("f"), down("m")), false, "nephew", "Neffe"},
+		{"grandniece", path(up("m"), down("m"), down("m"), down("f")), false, "grandniece", "Großnichte"},
+		{"nibling of unknown gender", path(up("m"), down("m"), down("")), false, "niece/nephew", "Nichte/Neffe"},
+		{"uncle", path(up("f"), up("m"), down("m")), false, "uncle", "Onkel"},
+		{"great-aunt", path(up("f"), up("m"), up("m"), down("f")), false, "great-aunt", "Großtante"},
+		{"first cousin", path(up("m"), up("m"), down("m"), down("m")), false, "first cousin", "Cousin"},
+		{"first cousin once removed", path(up("m"), up("m"), down("m"), down("m"), down("f")), false,
+			"first cousin once removed", "Cousine, um 1 Generation versetzt"},
+		{"second cousin", path(up("m"), up("m"), up("m"), down("m"), down("m"), down("m")), false,
+			"second cousin", "Cousin 2. Grades"},
+		{"third cousin twice removed",
+			path(up("m"), up("m"), up("m"), up("m"), down("m"), down("m"), down("m"), down("m"), down("m"), down("m")),
+			false, "third cousin twice removed", "Cousin 3. Grades, um 2 Generationen versetzt"},
+		{"wife", path(spouse("f")), false, "wife", "Ehefrau"},
+		{"father-in-law", path(spouse("f"), up("m")), false, "father-in-law", "Schwiegervater"},
+		{"stepson", path(spouse("f"), down("m")), false, "stepson", "Stiefsohn"},
+		{"daughter-in-law", path(down("m"), spouse("f")), false, "daughter-in-law", "Schwiegertochter"},
+		{"stepfather", path(up("f"), spouse("m")), false, "stepfather", "Stiefvater"},
+		{"brother-in-law by spouse", path(spouse("f"), up("m"), down("m")), false, "brother-in-law", "Schwager"},
+		{"sister-in-law by sibling", path(up("m"), down("m"), spouse("f")), false, "sister-in-law", "Schwägerin"},
+		{"stepbrother", path(up("f"), spouse("m"), down("m")), false, "stepbrother", "Stiefbruder"},
+		{"uncle by marriage", path(up("m"), up("m"), down("f"), spouse("m")), false,
+			"uncle by marriage", "Onkel (angeheiratet)"},
+		{"wife's nephew", path(spouse("f"), up("m"), down("m"), down("m")), false,
+			"wife's nephew", "Neffe der Ehefrau"},
+		{"husband's first cousin", path(spouse("m"), up("m"), up("m"), down("m"), down("f")), false,
+			"husband's first cousin", "Cousine des Ehemanns"},
+		{"second cousin's wife", path(up("m"), up("m"), up("m"), down("m"), down("m"), down("m"), spouse("f")), false,
+			"second cousin's wife", "Ehefrau des Cousins 2. Grades"},
+		{"son's mother", path(down("m"), up("f")), false, "son's mother", "Mutter des Sohns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe(tt.path, tt.isHalf)
+			if got[LANGUAGE_EN] != tt.en {
+				t.Errorf("en = %q, want %q", got[LANGUAGE_EN], tt.en)
+			}
+			if got[LANGUAGE_DE] != tt.de {
+				t.Errorf("de = %q, want %q", got[LANGUAGE_DE], tt.de)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package media stores uploaded files on disk by the SHA-256 hash of their content, so that every file is only
+// stored once no matter how often it is uploaded, and creates thumbnails of images.
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	goerrors "errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps the files below files/ and their thumbnails below thumbnails/ of its directory, where both are
+// sharded by the first two characters of the hash to keep the directories small
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Save writes the content unless a file with the same hash already exists, as it then has the same content
+func (s *Store) Save(hash string, content []byte) error {
+	return writeOnce(s.filePath(hash), content)
+}
+
+func (s *Store) SaveThumbnail(hash string, thumbnail []byte) error {
+	return writeOnce(s.thumbnailPath(hash), thumbnail)
+}
+
+func (s *Store) Open(hash string) (*os.File, error) {
+	return os.Open(s.filePath(hash))
+}
+
+func (s *Store) OpenThumbnail(hash string) (*os.File, error) {
+	return os.Open(s.thumbnailPath(hash))
+}
+
+// Delete removes the file together with its thumbnail, where missing files are no error
+func (s *Store) Delete(hash string) error {
+	for _, path := range []string{s.filePath(hash), s.thumbnailPath(hash)} {
+		if err := os.Remove(path); err != nil && !goerrors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) filePath(hash string) string {
+	return filepath.Join(s.dir, "files", hash[:2], hash)
+}
+
+func (s *Store) thumbnailPath(hash string) string {
+	return filepath.Join(s.dir, "thumbnails", hash[:2], hash+".jpg")
+}
+
+// writeOnce writes to a temporary file first, so that a file under its final path is always complete
+func writeOnce(path string, content []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
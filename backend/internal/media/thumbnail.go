@@ -0,0 +1,97 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// THUMBNAIL_MAX_SIZE is the maximum width and height of a thumbnail in pixels
+	THUMBNAIL_MAX_SIZE = 256
+	thumbnailQuality   = 80
+	// MAX_IMAGE_PIXELS limits the size of the decoded image, as a small but highly compressed file could otherwise
+	// exhaust the memory of the server
+	MAX_IMAGE_PIXELS = 50_000_000
+)
+
+var ErrImageTooLarge = errors.New("image is too large")
+
+// Thumbnail decodes the image and scales it down to fit into THUMBNAIL_MAX_SIZE, keeping its aspect ratio. Besides
+// the JPEG encoded thumbnail, it returns the size of the original image.
+func Thumbnail(content []byte) ([]byte, image.Point, error) {
+	// Only the header is decoded here, so that the size is known before any pixels are allocated
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > MAX_IMAGE_PIXELS {
+		return nil, image.Point{}, fmt.Errorf("%w: %dx%d pixels exceed the limit of %d pixels",
+			ErrImageTooLarge, config.Width, config.Height, MAX_IMAGE_PIXELS)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	size := img.Bounds().Size()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(img, THUMBNAIL_MAX_SIZE), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, image.Point{}, err
+	}
+	return buf.Bytes(), size, nil
+}
+
+// scaleDown averages all source pixels covered by a target pixel, which keeps thumbnails of photos smooth, while
+// images which are small enough already are only converted
+func scaleDown(img image.Image, maxSize int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	// Flatten transparency onto white, as JPEG has no alpha channel
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return src
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSize/srcW)
+	} else {
+		dstW = max(1, srcW*maxSize/srcH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+	return dst
+}
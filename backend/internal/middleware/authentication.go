@@ -42,9 +42,14 @@ func Authentication(sqlDb *sql.DB) func(next http.Handler) http.Handler {
 				errors.HandleHttpError(w, r, errors.NewUnauthorizedError(err.Error()))
 				return
 			}
+			if user.IsDisabled {
+				errors.HandleHttpError(w, r, errors.NewUnauthorizedError("Account is disabled"))
+				return
+			}
 
 			permissions := security.GetPermissionsForRole(user.Role)
 			ctx := r.Context()
+			ctx = context.WithValue(ctx, constants.AUTH_CONTEXT_USER_ID, user.Id)
 			ctx = context.WithValue(ctx, constants.AUTH_CONTEXT_USERNAME, user.Username)
 			ctx = context.WithValue(ctx, constants.AUTH_CONTEXT_ROLE, user.Role)
 			ctx = context.WithValue(ctx, constants.AUTH_CONTEXT_PERMISSIONS, permissions)
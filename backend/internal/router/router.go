@@ -6,27 +6,147 @@ import (
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/api"
 	"github.com/Sakrafux/family-tree-app/backend/internal/constants"
-	"github.com/kuzudb/go-kuzu"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/service"
 )
 
-func CreaterRouter(kuzuConn *kuzu.Connection, sqlDb *sql.DB) *AuthServeMux {
+func CreaterRouter(kuzuPool *db.KuzuPool, sqlDb *sql.DB, accessConfig service.AccessConfig, mediaPath string, trustedProxy bool) *AuthServeMux {
 	router := NewAuthServeMux()
 
-	apiHandler := api.NewHandler(kuzuConn, sqlDb)
+	apiHandler := api.NewHandler(kuzuPool, sqlDb, accessConfig, mediaPath)
 	apiRouter := NewAuthServeMux()
 
 	apiRouter.HandleFunc("GET /family-tree/{id}", apiHandler.GetFamilyTree)
 	apiRouter.HandleFunc("OPTIONS /family-tree/{id}", nullHandler)
+	apiRouter.HandleFunc("GET /family-tree/{id}/export.ged", apiHandler.GetFamilyTreeGedcom)
+	apiRouter.HandleFunc("OPTIONS /family-tree/{id}/export.ged", nullHandler)
+	apiRouter.HandleFunc("GET /persons/{id}/ancestors", apiHandler.GetAncestorTree)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/ancestors", nullHandler)
+	apiRouter.HandleFunc("GET /persons/{id}/descendants", apiHandler.GetDescendantTree)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/descendants", nullHandler)
+	apiRouter.HandleFunc("GET /persons/search", apiHandler.GetPersonSearch, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("OPTIONS /persons/search", nullHandler)
+	apiRouter.HandleFunc("GET /relationship", apiHandler.GetRelationship, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("OPTIONS /relationship", nullHandler)
+	apiRouter.HandleFunc("POST /persons", apiHandler.PostPerson, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons", nullHandler)
+	apiRouter.HandleFunc("PATCH /persons/{id}", apiHandler.PatchPerson, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /persons/{id}", apiHandler.DeletePerson, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}", nullHandler)
+	apiRouter.HandleFunc("POST /persons/{id}/events", apiHandler.PostEvent, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/events", nullHandler)
+	apiRouter.HandleFunc("POST /persons/{id}/citations", apiHandler.PostPersonCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/citations", nullHandler)
+	apiRouter.HandleFunc("GET /persons/{id}/media", apiHandler.GetPersonMedia)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/media", nullHandler)
+	apiRouter.HandleFunc("PUT /persons/{id}/media/{mediaId}", apiHandler.PutPersonMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /persons/{id}/media/{mediaId}", apiHandler.DeletePersonMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/media/{mediaId}", nullHandler)
+	apiRouter.HandleFunc("PATCH /events/{id}", apiHandler.PatchEvent, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /events/{id}", apiHandler.DeleteEvent, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /events/{id}", nullHandler)
+	apiRouter.HandleFunc("POST /events/{id}/citations", apiHandler.PostEventCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /events/{id}/citations", nullHandler)
+	apiRouter.HandleFunc("GET /events/{id}/media", apiHandler.GetEventMedia)
+	apiRouter.HandleFunc("OPTIONS /events/{id}/media", nullHandler)
+	apiRouter.HandleFunc("PUT /events/{id}/media/{mediaId}", apiHandler.PutEventMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /events/{id}/media/{mediaId}", apiHandler.DeleteEventMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /events/{id}/media/{mediaId}", nullHandler)
+	apiRouter.HandleFunc("GET /places/search", apiHandler.GetPlaceSearch, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("OPTIONS /places/search", nullHandler)
+	apiRouter.HandleFunc("POST /places", apiHandler.PostPlace, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /places", nullHandler)
+	apiRouter.HandleFunc("GET /places/{id}", apiHandler.GetPlace, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("PATCH /places/{id}", apiHandler.PatchPlace, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /places/{id}", apiHandler.DeletePlace, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /places/{id}", nullHandler)
+	apiRouter.HandleFunc("GET /places/{id}/persons", apiHandler.GetPlacePersons, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("OPTIONS /places/{id}/persons", nullHandler)
+	apiRouter.HandleFunc("POST /places/{id}/merge/{duplicateId}", apiHandler.PostPlaceMerge, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /places/{id}/merge/{duplicateId}", nullHandler)
+	apiRouter.HandleFunc("GET /sources", apiHandler.GetAllSources, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("POST /sources", apiHandler.PostSource, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /sources", nullHandler)
+	apiRouter.HandleFunc("GET /sources/{id}", apiHandler.GetSource, constants.AUTH_PERMISSION_READ)
+	apiRouter.HandleFunc("PATCH /sources/{id}", apiHandler.PatchSource, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /sources/{id}", apiHandler.DeleteSource, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /sources/{id}", nullHandler)
+	apiRouter.HandleFunc("PATCH /citations/{id}", apiHandler.PatchCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /citations/{id}", apiHandler.DeleteCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /citations/{id}", nullHandler)
+	apiRouter.HandleFunc("POST /media", apiHandler.PostMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /media", nullHandler)
+	apiRouter.HandleFunc("GET /media/{id}", apiHandler.GetMedia)
+	apiRouter.HandleFunc("PATCH /media/{id}", apiHandler.PatchMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /media/{id}", apiHandler.DeleteMedia, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /media/{id}", nullHandler)
+	apiRouter.HandleFunc("GET /media/{id}/file", apiHandler.GetMediaFile)
+	apiRouter.HandleFunc("OPTIONS /media/{id}/file", nullHandler)
+	apiRouter.HandleFunc("GET /media/{id}/thumbnail", apiHandler.GetMediaThumbnail)
+	apiRouter.HandleFunc("OPTIONS /media/{id}/thumbnail", nullHandler)
+	apiRouter.HandleFunc("GET /persons/{id}/history", apiHandler.GetPersonHistory, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/history", nullHandler)
+	apiRouter.HandleFunc("POST /persons/{id}/history/{revisionId}/restore", apiHandler.PostRestoreRevision, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /persons/{id}/history/{revisionId}/restore", nullHandler)
+	apiRouter.HandleFunc("POST /parent-relations", apiHandler.PostParentRelation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /parent-relations", nullHandler)
+	apiRouter.HandleFunc("DELETE /parent-relations/{parentId}/{childId}", apiHandler.DeleteParentRelation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /parent-relations/{parentId}/{childId}", nullHandler)
+	apiRouter.HandleFunc("POST /parent-relations/{parentId}/{childId}/citations", apiHandler.PostParentRelationCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /parent-relations/{parentId}/{childId}/citations", nullHandler)
+	apiRouter.HandleFunc("POST /marriage-relations", apiHandler.PostMarriageRelation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /marriage-relations", nullHandler)
+	apiRouter.HandleFunc("PATCH /marriage-relations/{person1Id}/{person2Id}", apiHandler.PatchMarriageRelationEnd, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /marriage-relations/{person1Id}/{person2Id}", apiHandler.DeleteMarriageRelation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /marriage-relations/{person1Id}/{person2Id}", nullHandler)
+	apiRouter.HandleFunc("POST /marriage-relations/{person1Id}/{person2Id}/citations", apiHandler.PostMarriageRelationCitation, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /marriage-relations/{person1Id}/{person2Id}/citations", nullHandler)
+	apiRouter.HandleFunc("POST /admin/sibling-relations/rebuild", apiHandler.PostRebuildSiblingRelations, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /admin/sibling-relations/rebuild", nullHandler)
+	apiRouter.HandleFunc("POST /admin/gedcom/import", apiHandler.PostGedcomImport, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /admin/gedcom/import", nullHandler)
+	apiRouter.HandleFunc("GET /admin/consistency-report", apiHandler.GetConsistencyReport, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /admin/consistency-report", nullHandler)
 	apiRouter.HandleFunc("GET /feedbacks", apiHandler.GetAllFeedbacks, constants.AUTH_PERMISSION_ADMIN)
 	apiRouter.HandleFunc("POST /feedbacks", apiHandler.PostFeedback, constants.AUTH_PERMISSION_ADMIN)
 	apiRouter.HandleFunc("OPTIONS /feedbacks", nullHandler)
 	apiRouter.HandleFunc("PATCH /feedbacks/{id}", apiHandler.PatchFeedbackResolve, constants.AUTH_PERMISSION_ADMIN)
 	apiRouter.HandleFunc("OPTIONS /feedbacks/{id}", nullHandler)
+	apiRouter.HandleFunc("GET /users", apiHandler.GetAllUsers, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("POST /users", apiHandler.PostUser, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users", nullHandler)
+	apiRouter.HandleFunc("PATCH /users/{id}", apiHandler.PatchUser, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /users/{id}", apiHandler.DeleteUser, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}", nullHandler)
+	apiRouter.HandleFunc("POST /users/{id}/password-reset-token", apiHandler.PostUserPasswordResetToken, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}/password-reset-token", nullHandler)
+	apiRouter.HandleFunc("DELETE /users/{id}/sessions", apiHandler.DeleteUserSessions, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}/sessions", nullHandler)
+	apiRouter.HandleFunc("DELETE /users/{id}/lockout", apiHandler.DeleteUserLockout, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}/lockout", nullHandler)
+	apiRouter.HandleFunc("GET /users/{id}/branches", apiHandler.GetUserBranches, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}/branches", nullHandler)
+	apiRouter.HandleFunc("PUT /users/{id}/branches/{branchId}", apiHandler.PutUserBranch, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /users/{id}/branches/{branchId}", apiHandler.DeleteUserBranch, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /users/{id}/branches/{branchId}", nullHandler)
+	apiRouter.HandleFunc("GET /branches", apiHandler.GetAllBranches, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("POST /branches", apiHandler.PostBranch, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /branches", nullHandler)
+	apiRouter.HandleFunc("PATCH /branches/{id}", apiHandler.PatchBranchPublic, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /branches/{id}", apiHandler.DeleteBranch, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /branches/{id}", nullHandler)
+	apiRouter.HandleFunc("GET /audit", apiHandler.GetAuditEntries, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /audit", nullHandler)
+	apiRouter.HandleFunc("GET /public-persons", apiHandler.GetPublicPersons, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /public-persons", nullHandler)
+	apiRouter.HandleFunc("PUT /public-persons/{id}", apiHandler.PutPublicPerson, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("DELETE /public-persons/{id}", apiHandler.DeletePublicPerson, constants.AUTH_PERMISSION_ADMIN)
+	apiRouter.HandleFunc("OPTIONS /public-persons/{id}", nullHandler)
 
 	router.Handle("/", apiRouter)
 
 	router.Handle("/public/", http.StripPrefix("/public", createPublicRouter()))
-	router.Handle("/security/", http.StripPrefix("/security", createSecurityRouter(sqlDb)))
+	router.Handle("/security/", http.StripPrefix("/security", createSecurityRouter(sqlDb, trustedProxy)))
 
 	routerWrapper := NewAuthServeMux()
 	routerWrapper.Handle("/api/", http.StripPrefix("/api", router))
@@ -44,13 +164,19 @@ func createPublicRouter() *AuthServeMux {
 	return publicRouter
 }
 
-func createSecurityRouter(sqlDb *sql.DB) *AuthServeMux {
-	securityHandler := api.NewSecurityHandler(sqlDb)
+func createSecurityRouter(sqlDb *sql.DB, trustedProxy bool) *AuthServeMux {
+	securityHandler := api.NewSecurityHandler(sqlDb, trustedProxy)
 	securityRouter := NewAuthServeMux()
 
 	securityRouter.HandleFunc("POST /login", securityHandler.Login)
 	securityRouter.HandleFunc("OPTIONS /login", nullHandler)
 	securityRouter.HandleFunc("GET /token", securityHandler.RefreshToken)
+	securityRouter.HandleFunc("POST /logout", securityHandler.Logout)
+	securityRouter.HandleFunc("OPTIONS /logout", nullHandler)
+	securityRouter.HandleFunc("POST /password", securityHandler.ChangePassword, constants.AUTH_PERMISSION_READ)
+	securityRouter.HandleFunc("OPTIONS /password", nullHandler)
+	securityRouter.HandleFunc("POST /password/reset", securityHandler.ResetPassword)
+	securityRouter.HandleFunc("OPTIONS /password/reset", nullHandler)
 
 	return securityRouter
 }
@@ -2,6 +2,8 @@ package security
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
 
 	"golang.org/x/crypto/bcrypt"
@@ -26,3 +28,14 @@ func VerifyPassword(password string, salt, storedHash []byte) bool {
 	err := bcrypt.CompareHashAndPassword(storedHash, combined)
 	return err == nil
 }
+
+// GenerateToken creates a random token to be handed out once, which is only ever stored via HashToken
+func GenerateToken() string {
+	return rand.Text()
+}
+
+// HashToken does not need a salt or bcrypt like passwords, as the tokens are random and long enough already
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const REFRESH_TOKEN_VALIDITY = time.Hour * 24 * 30
+
 type TokenData struct {
 	Id     int
 	Role   string
@@ -32,10 +34,12 @@ func CreateAccessToken(user *TokenData) (string, error) {
 	return tokenString, err
 }
 
-func CreateRefreshToken(user *TokenData) (string, error) {
+// CreateRefreshToken identifies the token via jti, so that it can be tracked and revoked on the server
+func CreateRefreshToken(user *TokenData, jti string) (string, error) {
 	claims := jwt.MapClaims{
+		"jti":     jti,
 		"user_id": fmt.Sprintf("%d", user.Id),
-		"exp":     time.Now().Add(time.Hour * 24 * 30).Unix(),
+		"exp":     time.Now().Add(REFRESH_TOKEN_VALIDITY).Unix(),
 		"iat":     time.Now().Unix(),
 		"role":    user.Role,
 		"node_id": user.NodeId,
@@ -99,3 +103,12 @@ func ExtractUserData(token *jwt.Token) (int, string, string, error) {
 	}
 	return userId, role, nodeId, nil
 }
+
+func ExtractTokenId(token *jwt.Token) (string, error) {
+	claims := token.Claims.(jwt.MapClaims)
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return "", fmt.Errorf("invalid refresh token")
+	}
+	return jti, nil
+}
@@ -10,3 +10,18 @@ var roleToPermissions = map[string][]string{
 func GetPermissionsForRole(role string) []string {
 	return roleToPermissions[role]
 }
+
+func IsValidRole(role string) bool {
+	_, ok := roleToPermissions[role]
+	return ok
+}
+
+// roleRanks orders the roles by their privileges, where anonymous users have no role and thus rank 0
+var roleRanks = map[string]int{
+	"user":  1,
+	"admin": 2,
+}
+
+func HasRoleAtLeast(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole]
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/constants"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/security"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const LIVING_PERSON_NAME = "Living"
+
+// LIVING_MAX_AGE is the age up to which persons are assumed to be alive, unless they are known to be dead
+const LIVING_MAX_AGE = 100
+
+// AccessConfig configures which persons viewers may see, depending on their role
+type AccessConfig struct {
+	// PrivacyMinRole is the lowest role which may see the details of living persons
+	PrivacyMinRole string
+	// UserVisibleDistance limits users without admin permissions to persons within this distance of their own node
+	UserVisibleDistance int
+}
+
+// Viewer is whoever requests the data, where an empty role stands for anonymous users
+type Viewer struct {
+	UserId int
+	Role   string
+	NodeId string
+}
+
+// AccessPolicy centrally decides which persons a viewer may see and in how much detail
+type AccessPolicy struct {
+	db     *sql.DB
+	pool   *db.KuzuPool
+	config AccessConfig
+}
+
+func NewAccessPolicy(sqlDb *sql.DB, pool *db.KuzuPool, config AccessConfig) *AccessPolicy {
+	return &AccessPolicy{db: sqlDb, pool: pool, config: config}
+}
+
+// mustRedact tells whether living persons have to be redacted for the viewer
+func (p *AccessPolicy) mustRedact(viewer Viewer) bool {
+	return !security.HasRoleAtLeast(viewer.Role, p.config.PrivacyMinRole)
+}
+
+// isScoped tells whether the viewer may only see parts of the graph, which applies to all authenticated users
+// without admin permissions
+func isScoped(viewer Viewer) bool {
+	return viewer.Role != "" && !lo.Contains(security.GetPermissionsForRole(viewer.Role), constants.AUTH_PERMISSION_ADMIN)
+}
+
+// visiblePersons returns the persons the viewer may see, or nil if the viewer is not restricted at all.
+// Anonymous viewers only see the public showcase, while scoped users see the persons within the configured distance
+// of their own node and within all branches granted to them.
+func (p *AccessPolicy) visiblePersons(viewer Viewer) (map[uuid.UUID]bool, error) {
+	if viewer.Role == "" {
+		return p.publicPersons()
+	}
+	if !isScoped(viewer) {
+		return nil, nil
+	}
+
+	visible := make(map[uuid.UUID]bool)
+	if err := p.addScope(visible, viewer.NodeId, db.ScopeWithinDistance(p.config.UserVisibleDistance)); err != nil {
+		return nil, err
+	}
+
+	branches, err := db.SelectBranchesOfUser(p.db, viewer.UserId)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err := p.addBranches(visible, branches); err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+// publicPersons consists of all persons marked as public and all public branches
+func (p *AccessPolicy) publicPersons() (map[uuid.UUID]bool, error) {
+	visible := make(map[uuid.UUID]bool)
+
+	nodeIds, err := db.SelectPublicPersons(p.db)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	for _, nodeId := range nodeIds {
+		if id, err := uuid.Parse(nodeId); err == nil {
+			visible[id] = true
+		}
+	}
+
+	branches, err := db.SelectPublicBranches(p.db)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err := p.addBranches(visible, branches); err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+func (p *AccessPolicy) addBranches(visible map[uuid.UUID]bool, branches []*db.Branch) error {
+	for _, branch := range branches {
+		if err := p.addScope(visible, branch.RootNodeId, db.ScopeDescendants(db.MAX_GENERATIONS)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addScope adds the root and all persons in the scope around it to the visible persons
+func (p *AccessPolicy) addScope(visible map[uuid.UUID]bool, rootId string, scope db.PersonScope) error {
+	id, err := uuid.Parse(rootId)
+	if err != nil {
+		// A dangling node does not grant anything, but must not lock the viewer out of the rest
+		return nil
+	}
+	persons, err := db.GetPersonsInScope(p.pool, id, scope)
+	if err != nil {
+		return kuzuError(err)
+	}
+	visible[id] = true
+	for _, person := range persons {
+		visible[person.Id] = true
+	}
+	return nil
+}
+
+// checkVisible rejects access to any of the persons outside of the visible ones, where nil allows everything
+func checkVisible(visible map[uuid.UUID]bool, ids ...uuid.UUID) error {
+	if visible == nil {
+		return nil
+	}
+	for _, id := range ids {
+		if !visible[id] {
+			return errors.NewForbiddenError(fmt.Sprintf("'%s' is outside of your visible family tree", id))
+		}
+	}
+	return nil
+}
+
+// filterFamilyTree removes all persons which are not visible, while relations to them remain as mere ids, just like
+// for persons outside of the requested scope
+func filterFamilyTree(dto *FamilyTreeDto, visible map[uuid.UUID]bool) {
+	if visible == nil {
+		return
+	}
+	for id := range dto.Persons {
+		if !visible[id] {
+			delete(dto.Persons, id)
+		}
+	}
+}
+
+// isLiving treats persons with an unknown birth year as living, as that is the safe assumption. For the same reason
+// uncertain dates of birth are taken at their latest possible day.
+func isLiving(person *db.Person) bool {
+	if person.IsDead != nil && *person.IsDead {
+		return false
+	}
+	_, latest := person.BirthDate().Bounds()
+	if latest == nil {
+		return true
+	}
+	return int32(time.Now().Year()-latest.Year()) < LIVING_MAX_AGE
+}
+
+// redactPerson returns a copy only showing the gender and the year of birth, so that the person keeps its place in
+// the tree without being identifiable
+func redactPerson(person *db.Person) *db.Person {
+	name := LIVING_PERSON_NAME
+	return &db.Person{
+		Id:            person.Id,
+		FirstName:     &name,
+		Gender:        person.Gender,
+		IsDead:        person.IsDead,
+		BirthDateYear: person.BirthDateYear,
+		// Without the qualifier the year would be misleading, e.g. for "before 1950"
+		BirthDateQualifier: person.BirthDateQualifier,
+		BirthDateToYear:    person.BirthDateToYear,
+		BirthDateCalendar:  person.BirthDateCalendar,
+	}
+}
+
+// redactFamilyTree redacts all living persons of the tree, together with the exact dates of their marriages
+func redactFamilyTree(dto *FamilyTreeDto) {
+	redacted := make(map[uuid.UUID]bool)
+	for id, person := range dto.Persons {
+		if isLiving(person.Person) {
+			person.Person = redactPerson(person.Person)
+			person.Age, person.AgeMin, person.AgeMax = calculateAge(person)
+			// Places and dates of events would identify the person just as well as the name
+			person.Events = make([]*db.Event, 0)
+			person.Citations = make([]*db.Citation, 0)
+			person.IsRedacted = true
+			redacted[id] = true
+		}
+	}
+
+	for id, person := range dto.Persons {
+		// Transcriptions of a relation, e.g. of a marriage record, reveal just as much about the other person
+		person.Citations = lo.Filter(person.Citations, func(item *db.Citation, index int) bool {
+			return !lo.SomeBy(citedPersonIds(item, nil), func(citedId uuid.UUID) bool { return redacted[citedId] })
+		})
+		for i := range person.Spouses {
+			spouse := &person.Spouses[i]
+			if redacted[id] || redacted[spouse.Id] {
+				spouse.SinceMonth, spouse.SinceDay = nil, nil
+				spouse.UntilMonth, spouse.UntilDay = nil, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/samber/lo"
+)
+
+const (
+	AUDIT_ACTION_CREATE               = "create"
+	AUDIT_ACTION_UPDATE               = "update"
+	AUDIT_ACTION_DELETE               = "delete"
+	AUDIT_ACTION_IMPORT               = "import"
+	AUDIT_ACTION_REBUILD              = "rebuild"
+	AUDIT_ACTION_LOGIN                = "login"
+	AUDIT_ACTION_LOGIN_FAILED         = "login-failed"
+	AUDIT_ACTION_LOGOUT               = "logout"
+	AUDIT_ACTION_PASSWORD_CHANGE      = "password-change"
+	AUDIT_ACTION_PASSWORD_RESET       = "password-reset"
+	AUDIT_ACTION_REFRESH_TOKEN_REUSED = "refresh-token-reused"
+)
+
+const (
+	AUDIT_ENTITY_PERSON            = "person"
+	AUDIT_ENTITY_EVENT             = "event"
+	AUDIT_ENTITY_PLACE             = "place"
+	AUDIT_ENTITY_SOURCE            = "source"
+	AUDIT_ENTITY_CITATION          = "citation"
+	AUDIT_ENTITY_MEDIA             = "media"
+	AUDIT_ENTITY_MEDIA_ATTACHMENT  = "media-attachment"
+	AUDIT_ENTITY_PARENT_RELATION   = "parent-relation"
+	AUDIT_ENTITY_MARRIAGE_RELATION = "marriage-relation"
+	AUDIT_ENTITY_SIBLING_RELATIONS = "sibling-relations"
+	AUDIT_ENTITY_GEDCOM            = "gedcom"
+	AUDIT_ENTITY_FEEDBACK          = "feedback"
+	AUDIT_ENTITY_USER              = "user"
+	AUDIT_ENTITY_SESSION           = "session"
+	AUDIT_ENTITY_PASSWORD_RESET    = "password-reset-token"
+	AUDIT_ENTITY_LOGIN_LOCKOUT     = "login-lockout"
+	AUDIT_ENTITY_BRANCH            = "branch"
+	AUDIT_ENTITY_USER_BRANCH       = "user-branch"
+	AUDIT_ENTITY_PUBLIC_PERSON     = "public-person"
+)
+
+// AuditLog records who changed what, together with the values before and after the change
+type AuditLog struct {
+	db *sql.DB
+}
+
+func NewAuditLog(db *sql.DB) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+// record never fails the action, as it has already been performed at this point. Without a database, e.g. for the
+// command line tools, nothing is recorded.
+func (a *AuditLog) record(username, action, entityType, entityId string, before, after any) {
+	if a == nil {
+		return
+	}
+
+	entry := &db.AuditEntry{
+		Timestamp:  time.Now(),
+		Username:   username,
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Before:     marshalAuditValue(before),
+		After:      marshalAuditValue(after),
+	}
+	if err := db.InsertAuditEntry(a.db, entry); err != nil {
+		log.Printf("[audit] Failed to record %s of %s '%s' by '%s': %v", action, entityType, entityId, username, err)
+	}
+}
+
+func marshalAuditValue(value any) []byte {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[audit] Failed to marshal value: %v", err)
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// auditEntityId joins the ids of relations the same way as their API paths
+func auditEntityId(ids ...string) string {
+	return strings.Join(ids, "/")
+}
+
+type AuditService struct {
+	db *sql.DB
+}
+
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// GetAuditEntries returns the newest entries matching all given filters
+func (s *AuditService) GetAuditEntries(filter db.AuditFilter) ([]*AuditEntryDto, error) {
+	entries, err := db.SelectAuditEntries(s.db, filter)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lo.Map(entries, func(item *db.AuditEntry, index int) *AuditEntryDto {
+		return &AuditEntryDto{
+			Id:         item.Id,
+			Timestamp:  item.Timestamp,
+			Username:   item.Username,
+			Action:     item.Action,
+			EntityType: item.EntityType,
+			EntityId:   item.EntityId,
+			Before:     item.Before,
+			After:      item.After,
+		}
+	}), nil
+}
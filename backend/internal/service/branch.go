@@ -0,0 +1,194 @@
+package service
+
+import (
+	"database/sql"
+	goerrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+type BranchService struct {
+	db    *sql.DB
+	pool  *db.KuzuPool
+	audit *AuditLog
+}
+
+func NewBranchService(db *sql.DB, pool *db.KuzuPool, audit *AuditLog) *BranchService {
+	return &BranchService{db: db, pool: pool, audit: audit}
+}
+
+func (s *BranchService) GetAllBranches() ([]*BranchDto, error) {
+	branches, err := db.SelectAllBranches(s.db)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return toBranchDtos(branches), nil
+}
+
+// CreateBranch names the subtree of all descendants of the root person, so that it can be granted to users
+func (s *BranchService) CreateBranch(request PostBranchRequest, actingUsername string) (*BranchDto, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, errors.NewUnprocessableEntityError("Name must not be empty")
+	}
+
+	existing, err := db.GetBranchByName(s.db, name)
+	if err != nil && !goerrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if existing != nil {
+		return nil, errors.NewConflictError(fmt.Sprintf("Branch '%s' already exists", name))
+	}
+
+	person, err := db.GetPersonById(s.pool, request.RootId)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if person == nil {
+		return nil, errors.NewUnprocessableEntityError(fmt.Sprintf("Person '%s' not found", request.RootId))
+	}
+
+	branch, err := db.InsertBranch(s.db, name, request.RootId.String())
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_BRANCH, strconv.Itoa(branch.Id), nil, branch)
+	return &BranchDto{branch}, nil
+}
+
+func (s *BranchService) DeleteBranch(id int, actingUsername string) error {
+	branch, err := s.getBranch(id)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteBranch(s.db, id); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_BRANCH, strconv.Itoa(id), branch, nil)
+	return nil
+}
+
+// SetBranchPublic adds the branch to or removes it from the public showcase for anonymous visitors
+func (s *BranchService) SetBranchPublic(id int, request PatchBranchPublicRequest, actingUsername string) (*BranchDto, error) {
+	before, err := s.getBranch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.UpdateBranchPublic(s.db, id, request.IsPublic); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	branch, err := s.getBranch(id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_BRANCH, strconv.Itoa(id), before, branch)
+	return &BranchDto{branch}, nil
+}
+
+func (s *BranchService) GetPublicPersons() ([]string, error) {
+	nodeIds, err := db.SelectPublicPersons(s.db)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return nodeIds, nil
+}
+
+// PublishPerson adds the single person to the public showcase, independent of any branch
+func (s *BranchService) PublishPerson(id uuid.UUID, actingUsername string) error {
+	person, err := db.GetPersonById(s.pool, id)
+	if err != nil {
+		return kuzuError(err)
+	}
+	if person == nil {
+		return errors.NewNotFoundError(fmt.Sprintf("Person '%s' not found", id))
+	}
+
+	if err := db.InsertPublicPerson(s.db, id.String()); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_PUBLIC_PERSON, id.String(), nil, nil)
+	return nil
+}
+
+func (s *BranchService) UnpublishPerson(id uuid.UUID, actingUsername string) error {
+	if err := db.DeletePublicPerson(s.db, id.String()); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PUBLIC_PERSON, id.String(), nil, nil)
+	return nil
+}
+
+func (s *BranchService) GetUserBranches(userId int) ([]*BranchDto, error) {
+	if err := s.ensureUserExists(userId); err != nil {
+		return nil, err
+	}
+
+	branches, err := db.SelectBranchesOfUser(s.db, userId)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return toBranchDtos(branches), nil
+}
+
+// GrantBranch lets the user see the branch in addition to the persons around their own node
+func (s *BranchService) GrantBranch(userId, branchId int, actingUsername string) error {
+	if err := s.ensureUserExists(userId); err != nil {
+		return err
+	}
+	if _, err := s.getBranch(branchId); err != nil {
+		return err
+	}
+
+	if err := db.InsertUserBranch(s.db, userId, branchId); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_USER_BRANCH,
+		auditEntityId(strconv.Itoa(userId), strconv.Itoa(branchId)), nil, nil)
+	return nil
+}
+
+func (s *BranchService) RevokeBranch(userId, branchId int, actingUsername string) error {
+	if err := db.DeleteUserBranch(s.db, userId, branchId); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_USER_BRANCH,
+		auditEntityId(strconv.Itoa(userId), strconv.Itoa(branchId)), nil, nil)
+	return nil
+}
+
+func (s *BranchService) getBranch(id int) (*db.Branch, error) {
+	branch, err := db.GetBranchById(s.db, id)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Branch '%d' not found", id))
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return branch, nil
+}
+
+func (s *BranchService) ensureUserExists(id int) error {
+	_, err := db.GetUserById(s.db, id)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return errors.NewNotFoundError(fmt.Sprintf("User '%d' not found", id))
+	}
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func toBranchDtos(branches []*db.Branch) []*BranchDto {
+	return lo.Map(branches, func(item *db.Branch, index int) *BranchDto {
+		return &BranchDto{item}
+	})
+}
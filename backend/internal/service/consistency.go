@@ -0,0 +1,45 @@
+package service
+
+import (
+	"github.com/Sakrafux/family-tree-app/backend/internal/consistency"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+)
+
+// CheckConsistency checks the whole family graph against the configured rules
+func (s *FamilyTreeService) CheckConsistency(config consistency.Config) (*consistency.Report, error) {
+	if config.MinParentAge < 0 || config.MaxLifespan < 0 {
+		return nil, errors.NewBadRequestError("thresholds must not be negative")
+	}
+
+	wg, chErr := initAsync(3)
+
+	chPersons := asyncDbCall(wg, chErr, func() ([]*db.Person, error) {
+		return db.GetAllPersons(s.pool)
+	})
+	chParentRelations := asyncDbCall(wg, chErr, func() ([]*db.ParentRelation, error) {
+		return db.GetAllParentRelations(s.pool)
+	})
+	chMarriageRelations := asyncDbCall(wg, chErr, func() ([]*db.MarriageRelation, error) {
+		return db.GetAllMarriageRelations(s.pool)
+	})
+
+	wg.Wait()
+
+	select {
+	case err := <-chErr:
+		return nil, kuzuError(err)
+	default:
+	}
+
+	graph := &consistency.Graph{
+		Persons:           <-chPersons,
+		ParentRelations:   <-chParentRelations,
+		MarriageRelations: <-chMarriageRelations,
+	}
+	report, err := consistency.Check(graph, config)
+	if err != nil {
+		return nil, errors.NewBadRequestError(err.Error())
+	}
+	return report, nil
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/Sakrafux/family-tree-app/backend/internal/db"
 	"github.com/google/uuid"
 )
@@ -22,13 +25,76 @@ type SiblingDto struct {
 
 type PersonDto struct {
 	*db.Person
+	// Age is exact for exact dates and otherwise estimated, while AgeMin and AgeMax bound the possible age of
+	// uncertain dates, where nil means unbounded
 	Age      *int32
+	AgeMin   *int32
+	AgeMax   *int32
 	Level    int
 	Distance int64
-	Parents  []uuid.UUID
-	Children []uuid.UUID
-	Siblings []SiblingDto
-	Spouses  []SpouseDto
+	// IsRedacted marks living persons whose details are hidden from the viewer
+	IsRedacted bool
+	Parents    []uuid.UUID
+	Children   []uuid.UUID
+	Siblings   []SiblingDto
+	Spouses    []SpouseDto
+	Events     []*db.Event
+	// Citations are all citations of the person, its events and its relations
+	Citations []*db.Citation
+}
+
+type PersonSearchResultDto struct {
+	Id            uuid.UUID
+	FirstName     *string
+	MiddleName    *string
+	LastName      *string
+	BirthName     *string
+	BirthDateYear *int32
+	DeathDateYear *int32
+	Score         float64
+}
+
+type PlaceDto struct {
+	*db.Place
+	// Path lists the names of the places the place is located within, from the innermost to the outermost
+	Path []string
+}
+
+type PlaceSearchResultDto struct {
+	Id   uuid.UUID
+	Name string
+	// MatchedName is the historical name which matched the query, if not the current name
+	MatchedName *string
+	Path        []string
+	Latitude    *float64
+	Longitude   *float64
+	Score       float64
+}
+
+type PlacePersonDto struct {
+	Id            uuid.UUID
+	FirstName     *string
+	MiddleName    *string
+	LastName      *string
+	BirthName     *string
+	BirthDateYear *int32
+	DeathDateYear *int32
+	// Events are only the events at the place
+	Events []*db.Event
+}
+
+type RelationshipDto struct {
+	From     uuid.UUID
+	To       uuid.UUID
+	Distance int
+	Paths    []*RelationshipPathDto
+}
+
+type RelationshipPathDto struct {
+	Persons []uuid.UUID
+	Steps   []db.PathStep
+	// Kinship maps the language to the term describing To relative to From, e.g. "en" to "great-aunt"
+	Kinship map[string]string
 }
 
 type FamilyTreeDto struct {
@@ -36,6 +102,45 @@ type FamilyTreeDto struct {
 	Persons map[uuid.UUID]*PersonDto
 }
 
+type PostPersonRequest struct {
+	db.Person
+}
+
+type PostEventRequest struct {
+	db.Event
+}
+
+type PostPlaceRequest struct {
+	db.Place
+}
+
+type PostSourceRequest struct {
+	db.Source
+}
+
+type PostCitationRequest struct {
+	db.Citation
+}
+
+type PatchMarriageEndRequest struct {
+	UntilYear  *int32
+	UntilMonth *int32
+	UntilDay   *int32
+}
+
+type GedcomImportPersonDto struct {
+	*db.Person
+	Xref string
+}
+
+type GedcomImportReportDto struct {
+	DryRun            bool
+	Persons           []*GedcomImportPersonDto
+	ParentRelations   []*db.ParentRelation
+	MarriageRelations []*db.MarriageRelation
+	Warnings          []string
+}
+
 type PostFeedbackRequest struct {
 	Text string
 }
@@ -48,6 +153,80 @@ type FeedbackDto struct {
 	*db.Feedback
 }
 
+type UserDto struct {
+	Id         int
+	Username   string
+	Role       string
+	NodeId     string
+	IsDisabled bool
+}
+
+type PostUserRequest struct {
+	Username string
+	Password string
+	Role     string
+	NodeId   string
+}
+
+// PatchUserRequest only changes the fields that are present
+type PatchUserRequest struct {
+	Username   *string
+	Role       *string
+	NodeId     *string
+	IsDisabled *bool
+}
+
+type BranchDto struct {
+	*db.Branch
+}
+
+type PostBranchRequest struct {
+	Name   string
+	RootId uuid.UUID
+}
+
+type PatchBranchPublicRequest struct {
+	IsPublic bool
+}
+
+type RevisionDto struct {
+	Id         int
+	EntityType string
+	EntityId   string
+	Version    int
+	Timestamp  time.Time
+	// Username is empty for the baseline of entities which existed before their first recorded change
+	Username  string
+	IsDeleted bool
+	Data      json.RawMessage
+}
+
+type AuditEntryDto struct {
+	Id         int
+	Timestamp  time.Time
+	Username   string
+	Action     string
+	EntityType string
+	EntityId   string
+	Before     json.RawMessage
+	After      json.RawMessage
+}
+
+type PasswordResetTokenDto struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string
+	NewPassword string
+}
+
+type ResetPasswordRequest struct {
+	Token       string
+	NewPassword string
+}
+
 type LoginRequest struct {
 	Username string
 	Password string
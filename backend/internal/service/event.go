@@ -0,0 +1,214 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	EVENT_TYPE_BIRTH       = "birth"
+	EVENT_TYPE_BAPTISM     = "baptism"
+	EVENT_TYPE_DEATH       = "death"
+	EVENT_TYPE_BURIAL      = "burial"
+	EVENT_TYPE_EMIGRATION  = "emigration"
+	EVENT_TYPE_IMMIGRATION = "immigration"
+	EVENT_TYPE_OTHER       = "other"
+)
+
+// eventTypeOrder sorts events without a date, or on the same date, in the order of a life
+var eventTypeOrder = []string{
+	EVENT_TYPE_BIRTH, EVENT_TYPE_BAPTISM, EVENT_TYPE_EMIGRATION, EVENT_TYPE_IMMIGRATION, EVENT_TYPE_OTHER,
+	EVENT_TYPE_DEATH, EVENT_TYPE_BURIAL,
+}
+
+// uniqueEventTypes can only happen once per person
+var uniqueEventTypes = []string{EVENT_TYPE_BIRTH, EVENT_TYPE_BAPTISM, EVENT_TYPE_DEATH, EVENT_TYPE_BURIAL}
+
+// CreateEvent checks the person, the place and the uniqueness of the event type within the transaction of the
+// creation, so that the checks still hold when the event is created
+func (s *FamilyTreeService) CreateEvent(personId uuid.UUID, req *PostEventRequest, actingUsername string) (*db.Event, error) {
+	event := req.Event
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	event.Id = id
+	event.PersonId = personId
+
+	normalizeEvent(&event)
+
+	var created *db.Event
+	err = s.transaction(func(tx *FamilyTreeService) error {
+		if _, err := tx.getPerson(personId); err != nil {
+			return err
+		}
+		if err := tx.validateEvent(&event); err != nil {
+			return err
+		}
+
+		created, err = db.CreateEvent(tx.pool, &event)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_EVENT, created.Id.String(), nil, created)
+
+	return created, nil
+}
+
+// UpdateEvent applies the JSON patch just like UpdatePerson, but the event always stays with its person
+func (s *FamilyTreeService) UpdateEvent(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Event, error) {
+	var before json.RawMessage
+	var updated *db.Event
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		event, err := tx.getEvent(id)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(event))
+		personId := event.PersonId
+
+		if err := json.Unmarshal(patch, event); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		event.Id = id
+		event.PersonId = personId
+
+		normalizeEvent(event)
+		if err := tx.validateEvent(event); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdateEvent(tx.pool, event)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_EVENT, id.String(), before, updated)
+
+	return updated, nil
+}
+
+func (s *FamilyTreeService) DeleteEvent(id uuid.UUID, actingUsername string) error {
+	var event *db.Event
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		event, err = tx.getEvent(id)
+		if err != nil {
+			return err
+		}
+
+		if err := db.DeleteEvent(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_EVENT, id.String(), event, nil)
+
+	return nil
+}
+
+func (s *FamilyTreeService) getEvent(id uuid.UUID) (*db.Event, error) {
+	event, err := db.GetEventById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if event == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Event '%s' not found", id))
+	}
+	return event, nil
+}
+
+// normalizeEvent trims the texts and treats empty strings as missing values
+func normalizeEvent(event *db.Event) {
+	event.Type = strings.ToLower(strings.TrimSpace(event.Type))
+	for _, field := range []**string{&event.Place, &event.Description} {
+		if *field == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(**field)
+		if len(trimmed) == 0 {
+			*field = nil
+		} else {
+			*field = &trimmed
+		}
+	}
+}
+
+func (s *FamilyTreeService) validateEvent(event *db.Event) error {
+	if !lo.Contains(eventTypeOrder, event.Type) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid Type '%s'", event.Type))
+	}
+	if err := validatePartialDate("Date", event.DateYear, event.DateMonth, event.DateDay); err != nil {
+		return err
+	}
+
+	if event.PlaceId != nil {
+		place, err := db.GetPlaceById(s.pool, *event.PlaceId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if place == nil {
+			return errors.NewUnprocessableEntityError(fmt.Sprintf("PlaceId '%s' is not a known place", *event.PlaceId))
+		}
+	}
+
+	if lo.Contains(uniqueEventTypes, event.Type) {
+		events, err := db.GetEventsByPersonId(s.pool, event.PersonId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		for _, other := range events {
+			if other.Id != event.Id && other.Type == event.Type {
+				return errors.NewConflictError(fmt.Sprintf("'%s' already has a %s event", event.PersonId, event.Type))
+			}
+		}
+	}
+
+	return nil
+}
+
+// relateEvents assigns the events to their persons in chronological order
+func relateEvents(dto *FamilyTreeDto, chEvents chan []*db.Event) {
+	for _, event := range <-chEvents {
+		if person, ok := dto.Persons[event.PersonId]; ok {
+			person.Events = append(person.Events, event)
+		}
+	}
+	for _, person := range dto.Persons {
+		slices.SortFunc(person.Events, compareEvents)
+	}
+}
+
+// compareEvents sorts by date, where unknown parts of the date come last, and by type for the same date
+func compareEvents(a, b *db.Event) int {
+	if c := cmp.Compare(derefDateInt32(a.DateYear), derefDateInt32(b.DateYear)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(derefDateInt32(a.DateMonth), derefDateInt32(b.DateMonth)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(derefDateInt32(a.DateDay), derefDateInt32(b.DateDay)); c != 0 {
+		return c
+	}
+	return cmp.Compare(slices.Index(eventTypeOrder, a.Type), slices.Index(eventTypeOrder, b.Type))
+}
@@ -2,114 +2,162 @@ package service
 
 import (
 	"cmp"
-	"fmt"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
 	"github.com/Sakrafux/family-tree-app/backend/internal/db"
-	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
 	"github.com/google/uuid"
-	"github.com/kuzudb/go-kuzu"
-	"github.com/samber/lo"
 )
 
 type FamilyTreeService struct {
-	conn *kuzu.Connection
+	pool    *db.KuzuPool
+	access  *AccessPolicy
+	audit   *AuditLog
+	history *History
 }
 
-func NewFamilyTreeService(conn *kuzu.Connection) *FamilyTreeService {
-	return &FamilyTreeService{conn: conn}
+func NewFamilyTreeService(pool *db.KuzuPool, access *AccessPolicy, audit *AuditLog, history *History) *FamilyTreeService {
+	return &FamilyTreeService{pool: pool, access: access, audit: audit, history: history}
 }
 
-func (s *FamilyTreeService) GetFamilyTree(id uuid.UUID, maxDistance int) (*FamilyTreeDto, error) {
-	chPersons, chDistances, chMarriageRelations, chParentRelations, chSiblingRelations, err := queryDbInParallel(s.conn, id)
+// GetFamilyTree returns all persons within maxDistance edges of any kind from the root person, together with all
+// their relations. Only these persons and their relations are loaded, so the cost depends on the size of the result
+// instead of the size of the whole graph.
+func (s *FamilyTreeService) GetFamilyTree(id uuid.UUID, maxDistance int, viewer Viewer) (*FamilyTreeDto, error) {
+	return s.getFamilyTreeInScope(id, db.ScopeWithinDistance(maxDistance), viewer)
+}
+
+// GetAncestorTree only follows parent relations upwards, so that the distance of every person is its generation
+func (s *FamilyTreeService) GetAncestorTree(id uuid.UUID, generations int, viewer Viewer) (*FamilyTreeDto, error) {
+	return s.getFamilyTreeInScope(id, db.ScopeAncestors(generations), viewer)
+}
+
+// GetDescendantTree only follows parent relations downwards, so that the distance of every person is its generation
+func (s *FamilyTreeService) GetDescendantTree(id uuid.UUID, generations int, viewer Viewer) (*FamilyTreeDto, error) {
+	return s.getFamilyTreeInScope(id, db.ScopeDescendants(generations), viewer)
+}
+
+func (s *FamilyTreeService) getFamilyTreeInScope(id uuid.UUID, scope db.PersonScope, viewer Viewer) (*FamilyTreeDto, error) {
+	// The visibility is checked first, so that persons outside of the visible tree cannot be told from unknown ones
+	visible, err := s.access.visiblePersons(viewer)
 	if err != nil {
 		return nil, err
 	}
-
-	dto := &FamilyTreeDto{Persons: make(map[uuid.UUID]*PersonDto)}
-
-	if err := mapPersonsByDistance(dto, chPersons, chDistances, id, maxDistance); err != nil {
+	if err := checkVisible(visible, id); err != nil {
 		return nil, err
 	}
-	if root, ok := dto.Persons[id]; ok {
-		dto.Root = root
-	} else {
-		return nil, errors.NewNotFoundError(fmt.Sprintf("'%s' not found", id))
+	root, err := s.getPerson(id)
+	if err != nil {
+		return nil, err
 	}
 
+	chPersons, chMarriageRelations, chParentRelations, chSiblingRelations, chEvents, chCitations, err := queryDbInParallel(s.pool, id, scope)
+	if err != nil {
+		return nil, err
+	}
+	persons := slices.Insert(<-chPersons, 0, &db.PersonDistance{Person: root, Distance: 0})
+
+	dto := &FamilyTreeDto{Persons: make(map[uuid.UUID]*PersonDto)}
+	mapPersons(dto, persons)
+	dto.Root = dto.Persons[id]
+
 	relateSpouses(dto, chMarriageRelations)
 	relateParentsAndChildren(dto, chParentRelations)
 	relateSiblings(dto, chSiblingRelations)
+	relateEvents(dto, chEvents)
+	relateCitations(dto, chCitations)
 	assignLevels(dto)
 
+	filterFamilyTree(dto, visible)
+	if s.access.mustRedact(viewer) {
+		redactFamilyTree(dto)
+	}
+
 	return dto, nil
 }
 
-func queryDbInParallel(conn *kuzu.Connection, id uuid.UUID) (chan []*db.Person, chan []*db.GraphDistance, chan []*db.MarriageRelation, chan []*db.ParentRelation, chan []*db.SiblingRelation, error) {
-	wg, chErr := initAsync(5)
+func queryDbInParallel(pool *db.KuzuPool, id uuid.UUID, scope db.PersonScope) (chan []*db.PersonDistance, chan []*db.MarriageRelation, chan []*db.ParentRelation, chan []*db.SiblingRelation, chan []*db.Event, chan []*db.Citation, error) {
+	wg, chErr := initAsync(6)
 
-	chPersons := asyncDbCall(wg, chErr, func() ([]*db.Person, error) {
-		return db.GetAllPersons(conn)
-	})
-	chDistances := asyncDbCall(wg, chErr, func() ([]*db.GraphDistance, error) {
-		return db.GetGraphDistancesForRootById(conn, id)
+	chPersons := asyncDbCall(wg, chErr, func() ([]*db.PersonDistance, error) {
+		return db.GetPersonsInScope(pool, id, scope)
 	})
 	chMarriageRelations := asyncDbCall(wg, chErr, func() ([]*db.MarriageRelation, error) {
-		return db.GetAllMarriageRelations(conn)
+		return db.GetMarriageRelationsInScope(pool, id, scope)
 	})
 	chParentRelations := asyncDbCall(wg, chErr, func() ([]*db.ParentRelation, error) {
-		return db.GetAllParentRelations(conn)
+		return db.GetParentRelationsInScope(pool, id, scope)
 	})
 	chSiblingRelations := asyncDbCall(wg, chErr, func() ([]*db.SiblingRelation, error) {
-		return db.GetAllSiblingRelations(conn)
+		return db.GetSiblingRelationsInScope(pool, id, scope)
+	})
+	chEvents := asyncDbCall(wg, chErr, func() ([]*db.Event, error) {
+		return db.GetEventsInScope(pool, id, scope)
+	})
+	chCitations := asyncDbCall(wg, chErr, func() ([]*db.Citation, error) {
+		return db.GetCitationsInScope(pool, id, scope)
 	})
 
 	wg.Wait()
 
 	select {
 	case err := <-chErr:
-		return nil, nil, nil, nil, nil, errors.NewInternalServerError(err.Error())
+		return nil, nil, nil, nil, nil, nil, kuzuError(err)
 	default:
 	}
 
-	return chPersons, chDistances, chMarriageRelations, chParentRelations, chSiblingRelations, nil
+	return chPersons, chMarriageRelations, chParentRelations, chSiblingRelations, chEvents, chCitations, nil
 }
 
-func mapPersonsByDistance(dto *FamilyTreeDto, chPersons chan []*db.Person, chDistances chan []*db.GraphDistance, id uuid.UUID, maxDistance int) error {
-	personMap := lo.SliceToMap(<-chPersons, func(item *db.Person) (uuid.UUID, *db.Person) {
-		return item.Id, item
-	})
-
-	if _, ok := personMap[id]; !ok {
-		return errors.NewNotFoundError(fmt.Sprintf("'%s' not found", id))
-	}
-
-	distances := slices.Insert(<-chDistances, 0, &db.GraphDistance{Id: id, Distance: 0})
-	for _, distance := range distances {
-		// Only map the persons within maxDistance
-		if distance.Distance > int64(maxDistance) {
-			// Because the distances are sorted, we can stop iterating
-			break
+func mapPersons(dto *FamilyTreeDto, persons []*db.PersonDistance) {
+	for _, p := range persons {
+		// Kuzu may report the root again via a cycle, which must not override its distance of 0
+		if _, ok := dto.Persons[p.Id]; ok {
+			continue
 		}
 
 		person := &PersonDto{
-			Person:   personMap[distance.Id],
-			Distance: distance.Distance,
+			Person:   p.Person,
+			Distance: p.Distance,
 			Parents:  make([]uuid.UUID, 0),
 			Children: make([]uuid.UUID, 0),
 			Siblings: make([]SiblingDto, 0),
 			Spouses:  make([]SpouseDto, 0),
+			Events:   make([]*db.Event, 0),
 		}
-		person.Age = calculateAge(person)
+		person.Age, person.AgeMin, person.AgeMax = calculateAge(person)
+
+		dto.Persons[p.Id] = person
+	}
+}
+
+// calculateAge returns the age at death or today, together with its bounds
+func calculateAge(person *PersonDto) (age, ageMin, ageMax *int32) {
+	to := dates.Today()
+	if person.IsDead != nil && *person.IsDead {
+		to = person.DeathDate()
+	}
+	from := person.BirthDate()
 
-		dto.Persons[distance.Id] = person
+	ageMin, ageMax = dates.AgeRange(from, to)
+	if from.IsExact() && to.IsExact() {
+		return calculateExactAge(person), ageMin, ageMax
 	}
 
-	return nil
+	// Open-ended dates like "after 1920" have no sensible estimate, only a bound
+	if ageMin == nil || ageMax == nil {
+		return nil, ageMin, ageMax
+	}
+	fromEstimate, _ := from.Estimate()
+	toEstimate, _ := to.Estimate()
+	estimate := max(dates.YearsBetween(fromEstimate, toEstimate), 0)
+	return &estimate, ageMin, ageMax
 }
 
-func calculateAge(person *PersonDto) *int32 {
+// calculateExactAge treats unknown months and days as if the birthday had already passed
+func calculateExactAge(person *PersonDto) *int32 {
 	currentDay := int32(time.Now().Day())
 	currentMonth := int32(time.Now().Month())
 	currentYear := int32(time.Now().Year())
@@ -201,19 +249,36 @@ func relateParentsAndChildren(dto *FamilyTreeDto, chParentRelations chan []*db.P
 		}
 	}
 
-	// Sort parents and children deterministically by birthdate
+	// Sort parents deterministically with the father first and children by birthdate
 	for _, person := range dto.Persons {
-		if len(person.Parents) == 2 {
-			if parent1, ok := dto.Persons[person.Parents[0]]; ok && *parent1.Gender == "f" {
-				person.Parents = []uuid.UUID{person.Parents[1], person.Parents[0]}
+		slices.SortFunc(person.Parents, func(a, b uuid.UUID) int {
+			if c := cmp.Compare(parentRank(dto, a), parentRank(dto, b)); c != 0 {
+				return c
 			}
-		}
+			return strings.Compare(a.String(), b.String())
+		})
 		slices.SortFunc(person.Children, func(a, b uuid.UUID) int {
 			return compareByBirthDate(dto, a, b)
 		})
 	}
 }
 
+// parentRank orders fathers before mothers, while parents outside the tree have an unknown gender in between
+func parentRank(dto *FamilyTreeDto, id uuid.UUID) int {
+	parent, ok := dto.Persons[id]
+	if !ok || parent.Gender == nil {
+		return 1
+	}
+	switch *parent.Gender {
+	case "m":
+		return 0
+	case "f":
+		return 2
+	default:
+		return 1
+	}
+}
+
 func relateSiblings(dto *FamilyTreeDto, chSiblingRelations chan []*db.SiblingRelation) {
 	for _, siblingRelation := range <-chSiblingRelations {
 		sibling1 := SiblingDto{Id: siblingRelation.Person2Id, IsHalf: siblingRelation.IsHalf}
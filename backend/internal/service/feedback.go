@@ -2,6 +2,9 @@ package service
 
 import (
 	"database/sql"
+	goerrors "errors"
+	"fmt"
+	"strconv"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/db"
 	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
@@ -9,11 +12,12 @@ import (
 )
 
 type FeedbackService struct {
-	db *sql.DB
+	db    *sql.DB
+	audit *AuditLog
 }
 
-func NewFeedbackService(db *sql.DB) *FeedbackService {
-	return &FeedbackService{db: db}
+func NewFeedbackService(db *sql.DB, audit *AuditLog) *FeedbackService {
+	return &FeedbackService{db: db, audit: audit}
 }
 
 func (s *FeedbackService) GetAllFeedbacks() ([]*FeedbackDto, error) {
@@ -29,21 +33,32 @@ func (s *FeedbackService) GetAllFeedbacks() ([]*FeedbackDto, error) {
 	return dtos, nil
 }
 
-func (s *FeedbackService) PostFeedback(text string) (*FeedbackDto, error) {
+func (s *FeedbackService) PostFeedback(text string, actingUsername string) (*FeedbackDto, error) {
 	fb, err := db.InsertFeedback(s.db, text)
 	if err != nil {
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_FEEDBACK, strconv.Itoa(fb.Id), nil, fb)
 
 	dto := &FeedbackDto{fb}
 
 	return dto, nil
 }
 
-func (s *FeedbackService) ResolveFeedback(id int, isResolved bool) error {
-	err := db.UpdateFeedbackIsResolved(s.db, id, isResolved)
+func (s *FeedbackService) ResolveFeedback(id int, isResolved bool, actingUsername string) error {
+	fb, err := db.SelectFeedbackById(s.db, id)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return errors.NewNotFoundError(fmt.Sprintf("Feedback '%d' not found", id))
+	}
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	err = db.UpdateFeedbackIsResolved(s.db, id, isResolved)
 	if err != nil {
 		return errors.NewInternalServerError(err.Error())
 	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_FEEDBACK, strconv.Itoa(id),
+		map[string]bool{"IsResolved": fb.IsResolved}, map[string]bool{"IsResolved": isResolved})
 	return nil
 }
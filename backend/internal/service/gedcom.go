@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/gedcom"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// ImportGedcom adds all individuals and families of the GEDCOM file as new persons and relations to the graph.
+// Individuals failing the usual person validation are skipped together with their relations.
+// With dryRun, nothing is written and the report only shows what would have been created. Otherwise everything is
+// written within one transaction, so a failed import leaves the graph unchanged.
+func (s *FamilyTreeService) ImportGedcom(reader io.Reader, dryRun bool, actingUsername string) (*GedcomImportReportDto, error) {
+	records, err := gedcom.Parse(reader)
+	if err != nil {
+		return nil, errors.NewUnprocessableEntityError(err.Error())
+	}
+	imp, err := gedcom.Map(records)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	report := &GedcomImportReportDto{
+		DryRun:            dryRun,
+		Persons:           make([]*GedcomImportPersonDto, 0),
+		MarriageRelations: make([]*db.MarriageRelation, 0),
+		Warnings:          imp.Warnings,
+	}
+
+	skipped := make(map[uuid.UUID]bool)
+	for _, person := range imp.Persons {
+		normalizePerson(person)
+		if err := validatePerson(person); err != nil {
+			skipped[person.Id] = true
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: skipped individual: %s", imp.Xrefs[person.Id], err.Error()))
+			continue
+		}
+		report.Persons = append(report.Persons, &GedcomImportPersonDto{Person: person, Xref: imp.Xrefs[person.Id]})
+	}
+	report.ParentRelations = lo.Filter(imp.ParentRelations, func(item *db.ParentRelation, index int) bool {
+		return !skipped[item.ParentId] && !skipped[item.ChildId]
+	})
+	for _, relation := range imp.MarriageRelations {
+		if skipped[relation.Person1Id] || skipped[relation.Person2Id] {
+			continue
+		}
+		if err := validateMarriageRelation(relation); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s & %s: skipped marriage: %s",
+				imp.Xrefs[relation.Person1Id], imp.Xrefs[relation.Person2Id], err.Error()))
+			continue
+		}
+		report.MarriageRelations = append(report.MarriageRelations, relation)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	err = s.pool.Transaction(func(tx *db.KuzuPool) error {
+		for _, person := range report.Persons {
+			if _, err := db.CreatePerson(tx, person.Person); err != nil {
+				return err
+			}
+		}
+		for _, relation := range report.ParentRelations {
+			if err := db.CreateParentRelation(tx, relation); err != nil {
+				return err
+			}
+		}
+		for _, relation := range report.MarriageRelations {
+			if _, err := db.CreateMarriageRelation(tx, relation); err != nil {
+				return err
+			}
+		}
+		// Only new persons are involved, but for larger imports a single rebuild is cheaper than one per child
+		return db.RebuildAllSiblingRelations(tx)
+	})
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	// The single entry only summarizes the import, as the created persons can be looked up by their ids
+	s.audit.record(actingUsername, AUDIT_ACTION_IMPORT, AUDIT_ENTITY_GEDCOM, "", nil, map[string]any{
+		"Persons": lo.Map(report.Persons, func(item *GedcomImportPersonDto, index int) uuid.UUID {
+			return item.Id
+		}),
+		"ParentRelations":   len(report.ParentRelations),
+		"MarriageRelations": len(report.MarriageRelations),
+	})
+
+	return report, nil
+}
+
+// ExportGedcom serializes exactly the persons returned by GetFamilyTree for the same parameters, including the
+// redaction of living persons
+func (s *FamilyTreeService) ExportGedcom(id uuid.UUID, maxDistance int, viewer Viewer) ([]byte, error) {
+	dto, err := s.GetFamilyTree(id, maxDistance, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	personDtos := lo.Values(dto.Persons)
+	slices.SortFunc(personDtos, func(a, b *PersonDto) int {
+		if a.Distance != b.Distance {
+			return cmp.Compare(a.Distance, b.Distance)
+		}
+		return compareByBirthDate(dto, a.Id, b.Id)
+	})
+
+	persons := make([]*db.Person, 0, len(personDtos))
+	parentRelations := make([]*db.ParentRelation, 0)
+	marriageRelations := make([]*db.MarriageRelation, 0)
+	for _, person := range personDtos {
+		persons = append(persons, person.Person)
+		for _, parentId := range person.Parents {
+			parentRelations = append(parentRelations, &db.ParentRelation{ParentId: parentId, ChildId: person.Id})
+		}
+		for _, spouse := range person.Spouses {
+			// Every marriage is contained in both spouses, but only needs to be exported once
+			if strings.Compare(person.Id.String(), spouse.Id.String()) > 0 {
+				continue
+			}
+			marriageRelations = append(marriageRelations, &db.MarriageRelation{
+				Person1Id:  person.Id,
+				Person2Id:  spouse.Id,
+				SinceYear:  spouse.SinceYear,
+				SinceMonth: spouse.SinceMonth,
+				SinceDay:   spouse.SinceDay,
+				UntilYear:  spouse.UntilYear,
+				UntilMonth: spouse.UntilMonth,
+				UntilDay:   spouse.UntilDay,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gedcom.Write(&buf, persons, parentRelations, marriageRelations); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// History keeps every version of persons and relations, so that earlier versions can be restored
+type History struct {
+	db *sql.DB
+}
+
+func NewHistory(db *sql.DB) *History {
+	return &History{db: db}
+}
+
+// record stores the new version of the entity, where after is nil for deletions. Entities which existed before the
+// history was introduced get their previous version as baseline, so that even their first change can be undone.
+// Just like the audit log, failures never fail the change itself and nothing is recorded without a database.
+func (h *History) record(username, entityType, entityId string, before, after any) {
+	if h == nil {
+		return
+	}
+
+	if before != nil {
+		hasRevisions, err := db.HasRevisions(h.db, entityType, entityId)
+		if err != nil {
+			log.Printf("[history] Failed to look up revisions of %s '%s': %v", entityType, entityId, err)
+			return
+		}
+		if !hasRevisions {
+			h.insert("", entityType, entityId, before)
+		}
+	}
+	h.insert(username, entityType, entityId, after)
+}
+
+func (h *History) insert(username, entityType, entityId string, data any) {
+	revision := &db.Revision{
+		EntityType: entityType,
+		EntityId:   entityId,
+		Timestamp:  time.Now(),
+		Username:   username,
+		Data:       marshalAuditValue(data),
+	}
+	if err := db.InsertRevision(h.db, revision); err != nil {
+		log.Printf("[history] Failed to record revision of %s '%s': %v", entityType, entityId, err)
+	}
+}
+
+// marriageEntityId does not depend on the direction of the edge, as marriages are symmetric
+func marriageEntityId(person1Id, person2Id uuid.UUID) string {
+	ids := []string{person1Id.String(), person2Id.String()}
+	if cmp.Less(ids[1], ids[0]) {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	return auditEntityId(ids...)
+}
+
+// recordChange writes the change to both the audit log and the history
+func (s *FamilyTreeService) recordChange(actingUsername, action, entityType, entityId string, before, after any) {
+	s.audit.record(actingUsername, action, entityType, entityId, before, after)
+	s.history.record(actingUsername, entityType, entityId, before, after)
+}
+
+// GetPersonHistory returns all revisions of the person and of the relations the person is part of, newest first
+func (s *FamilyTreeService) GetPersonHistory(id uuid.UUID) ([]*RevisionDto, error) {
+	if s.history == nil {
+		return nil, errors.NewInternalServerError("history is not available")
+	}
+
+	revisions, err := db.SelectRevisionsOfPerson(s.history.db, AUDIT_ENTITY_PERSON, id.String())
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if len(revisions) == 0 {
+		// Persons without any changes have no history, but unknown persons should still be reported as such
+		if _, err := s.getPerson(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return lo.Map(revisions, func(item *db.Revision, index int) *RevisionDto {
+		return toRevisionDto(item)
+	}), nil
+}
+
+// RestoreRevision reapplies the revision through the usual write operations, so that it is validated, audited and
+// recorded as a new revision itself. Restoring the revision of a deletion deletes the entity again.
+func (s *FamilyTreeService) RestoreRevision(personId uuid.UUID, revisionId int, actingUsername string) error {
+	if s.history == nil {
+		return errors.NewInternalServerError("history is not available")
+	}
+
+	revision, err := db.GetRevisionById(s.history.db, revisionId)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return errors.NewNotFoundError(fmt.Sprintf("Revision '%d' not found", revisionId))
+	}
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if !revisionConcernsPerson(revision, personId) {
+		return errors.NewNotFoundError(fmt.Sprintf("Revision '%d' does not belong to '%s'", revisionId, personId))
+	}
+
+	switch revision.EntityType {
+	case AUDIT_ENTITY_PERSON:
+		return s.restorePerson(personId, revision.Data, actingUsername)
+	case AUDIT_ENTITY_PARENT_RELATION:
+		return s.restoreParentRelation(revision, actingUsername)
+	case AUDIT_ENTITY_MARRIAGE_RELATION:
+		return s.restoreMarriageRelation(revision, actingUsername)
+	default:
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("Revisions of %s cannot be restored", revision.EntityType))
+	}
+}
+
+func revisionConcernsPerson(revision *db.Revision, personId uuid.UUID) bool {
+	if revision.EntityType == AUDIT_ENTITY_PERSON {
+		return revision.EntityId == personId.String()
+	}
+	return lo.Contains(strings.Split(revision.EntityId, "/"), personId.String())
+}
+
+func (s *FamilyTreeService) restorePerson(id uuid.UUID, data []byte, actingUsername string) error {
+	existing, err := db.GetPersonById(s.pool, id)
+	if err != nil {
+		return kuzuError(err)
+	}
+
+	if data == nil {
+		if existing == nil {
+			return nil
+		}
+		return s.DeletePerson(id, actingUsername)
+	}
+	if existing != nil {
+		_, err := s.UpdatePerson(id, data, actingUsername)
+		return err
+	}
+
+	var person db.Person
+	if err := json.Unmarshal(data, &person); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	person.Id = id
+	_, err = s.createPerson(&person, actingUsername)
+	return err
+}
+
+func (s *FamilyTreeService) restoreParentRelation(revision *db.Revision, actingUsername string) error {
+	parentId, childId, err := parseRelationEntityId(revision.EntityId)
+	if err != nil {
+		return err
+	}
+	relation := db.ParentRelation{ParentId: parentId, ChildId: childId}
+
+	existing, err := db.GetParentRelation(s.pool, relation.ParentId, relation.ChildId)
+	if err != nil {
+		return kuzuError(err)
+	}
+
+	if revision.Data == nil {
+		if existing == nil {
+			return nil
+		}
+		return s.RemoveParentRelation(relation.ParentId, relation.ChildId, actingUsername)
+	}
+	if existing != nil {
+		return nil
+	}
+	_, err = s.AddParentRelation(&relation, actingUsername)
+	return err
+}
+
+func (s *FamilyTreeService) restoreMarriageRelation(revision *db.Revision, actingUsername string) error {
+	person1Id, person2Id, err := parseRelationEntityId(revision.EntityId)
+	if err != nil {
+		return err
+	}
+
+	existing, err := db.GetMarriageRelation(s.pool, person1Id, person2Id)
+	if err != nil {
+		return kuzuError(err)
+	}
+
+	if revision.Data == nil {
+		if existing == nil {
+			return nil
+		}
+		return s.RemoveMarriageRelation(person1Id, person2Id, actingUsername)
+	}
+
+	var relation db.MarriageRelation
+	if err := json.Unmarshal(revision.Data, &relation); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if existing == nil {
+		_, err = s.AddMarriageRelation(&relation, actingUsername)
+		return err
+	}
+	_, err = s.updateMarriageRelation(person1Id, person2Id, func(stored *db.MarriageRelation) {
+		// Keep the direction of the stored edge, so that it is updated instead of looked up in vain
+		relation.Person1Id, relation.Person2Id = stored.Person1Id, stored.Person2Id
+		*stored = relation
+	}, actingUsername)
+	return err
+}
+
+func parseRelationEntityId(entityId string) (uuid.UUID, uuid.UUID, error) {
+	parts := strings.Split(entityId, "/")
+	if len(parts) != 2 {
+		return uuid.Nil, uuid.Nil, errors.NewInternalServerError(fmt.Sprintf("invalid relation id '%s'", entityId))
+	}
+	id1, err1 := uuid.Parse(parts[0])
+	id2, err2 := uuid.Parse(parts[1])
+	if err := goerrors.Join(err1, err2); err != nil {
+		return uuid.Nil, uuid.Nil, errors.NewInternalServerError(err.Error())
+	}
+	return id1, id2, nil
+}
+
+func toRevisionDto(revision *db.Revision) *RevisionDto {
+	return &RevisionDto{
+		Id:         revision.Id,
+		EntityType: revision.EntityType,
+		EntityId:   revision.EntityId,
+		Version:    revision.Version,
+		Timestamp:  revision.Timestamp,
+		Username:   revision.Username,
+		IsDeleted:  revision.Data == nil,
+		Data:       revision.Data,
+	}
+}
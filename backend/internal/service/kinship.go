@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/kinship"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// GetRelationship returns all shortest paths between both persons, each with the kinship term describing how the
+// second person is related to the first one
+func (s *FamilyTreeService) GetRelationship(fromId, toId uuid.UUID, viewer Viewer) (*RelationshipDto, error) {
+	// Just like for the family tree, hidden persons must not be told from unknown ones
+	visible, err := s.access.visiblePersons(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVisible(visible, fromId, toId); err != nil {
+		return nil, err
+	}
+	from, err := s.getPerson(fromId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getPerson(toId); err != nil {
+		return nil, err
+	}
+
+	dto := &RelationshipDto{From: fromId, To: toId, Paths: make([]*RelationshipPathDto, 0)}
+
+	if fromId == toId {
+		path := &db.RelationshipPath{Persons: []*db.Person{from}, Steps: make([]db.PathStep, 0)}
+		dto.Paths = append(dto.Paths, toRelationshipPathDto(path, false))
+		return dto, nil
+	}
+
+	paths, err := db.GetShortestRelationshipPaths(s.pool, fromId, toId)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if len(paths) == 0 {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("'%s' and '%s' are not related", fromId, toId))
+	}
+
+	isHalf, err := s.isHalfSibling(fromId, toId)
+	if err != nil {
+		return nil, err
+	}
+
+	dto.Distance = len(paths[0].Steps)
+	for _, path := range paths {
+		dto.Paths = append(dto.Paths, toRelationshipPathDto(path, isHalf))
+	}
+
+	return dto, nil
+}
+
+// isHalfSibling only holds if both parents are known for both persons, as a missing parent is not a different one
+func (s *FamilyTreeService) isHalfSibling(id1, id2 uuid.UUID) (bool, error) {
+	parents1, err := db.GetParentRelationsByChildId(s.pool, id1)
+	if err != nil {
+		return false, kuzuError(err)
+	}
+	parents2, err := db.GetParentRelationsByChildId(s.pool, id2)
+	if err != nil {
+		return false, kuzuError(err)
+	}
+	if len(parents1) != maxParentsPerChild || len(parents2) != maxParentsPerChild {
+		return false, nil
+	}
+
+	parentIds := func(item *db.ParentRelation, index int) uuid.UUID {
+		return item.ParentId
+	}
+	shared := lo.Intersect(lo.Map(parents1, parentIds), lo.Map(parents2, parentIds))
+	return len(shared) == 1, nil
+}
+
+func toRelationshipPathDto(path *db.RelationshipPath, isHalf bool) *RelationshipPathDto {
+	return &RelationshipPathDto{
+		Persons: lo.Map(path.Persons, func(item *db.Person, index int) uuid.UUID {
+			return item.Id
+		}),
+		Steps:   path.Steps,
+		Kinship: kinship.Describe(path, isHalf),
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+)
+
+const (
+	// USER_LOGIN_ATTEMPTS failed logins are allowed per username before it is locked
+	USER_LOGIN_ATTEMPTS = 5
+	// IP_LOGIN_ATTEMPTS is higher than for usernames, as multiple users may share an address
+	IP_LOGIN_ATTEMPTS = 20
+	// LOGIN_LOCKOUT is the duration of the first lockout, which doubles with every further failure
+	LOGIN_LOCKOUT     = 30 * time.Second
+	MAX_LOGIN_LOCKOUT = time.Hour
+	// LOGIN_ATTEMPTS_WINDOW after the last failure, the failures are forgotten
+	LOGIN_ATTEMPTS_WINDOW = 24 * time.Hour
+)
+
+func userLockoutKey(username string) string {
+	return "user:" + username
+}
+
+func ipLockoutKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLockout rejects the login before verifying the password, so that locked logins do not cost any bcrypt
+func (s *SecurityService) checkLoginLockout(keys ...string) error {
+	lockedUntil, err := db.GetLoginLockedUntil(s.db, keys...)
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	if lockedUntil != nil {
+		if remaining := time.Until(*lockedUntil); remaining > 0 {
+			return errors.NewTooManyRequestsError("Too many failed login attempts, try again later", remaining)
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure locks the key once it has exceeded its allowed attempts, where every further failure doubles
+// the lockout
+func (s *SecurityService) recordLoginFailure(key string, allowedAttempts int) error {
+	now := time.Now().Truncate(time.Second)
+	failures, err := db.RecordLoginFailure(s.db, key, now, now.Add(-LOGIN_ATTEMPTS_WINDOW))
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if failures < allowedAttempts {
+		return nil
+	}
+
+	lockout := MAX_LOGIN_LOCKOUT
+	if exponent := failures - allowedAttempts; exponent < 16 {
+		lockout = min(LOGIN_LOCKOUT<<exponent, MAX_LOGIN_LOCKOUT)
+	}
+	if err := db.LockLogin(s.db, key, now.Add(lockout)); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/media"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// mediaContentTypes are the accepted uploads, where all images get a thumbnail
+var mediaContentTypes = []string{"image/jpeg", "image/png", "image/gif", "application/pdf"}
+
+type MediaService struct {
+	pool   *db.KuzuPool
+	store  *media.Store
+	access *AccessPolicy
+	audit  *AuditLog
+}
+
+func NewMediaService(pool *db.KuzuPool, store *media.Store, access *AccessPolicy, audit *AuditLog) *MediaService {
+	return &MediaService{pool: pool, store: store, access: access, audit: audit}
+}
+
+// UploadMedia stores the file unless the very same content has been uploaded before, in which case the existing
+// media is returned instead. The returned flag tells whether the media has been created.
+func (s *MediaService) UploadMedia(fileName string, content []byte, caption *string, actingUsername string) (*db.Media, bool, error) {
+	contentType := http.DetectContentType(content)
+	if !lo.Contains(mediaContentTypes, contentType) {
+		return nil, false, errors.NewUnprocessableEntityError(fmt.Sprintf("unsupported content type '%s'", contentType))
+	}
+
+	hash := media.Hash(content)
+	existing, err := db.GetMediaByHash(s.pool, hash)
+	if err != nil {
+		return nil, false, kuzuError(err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, false, errors.NewInternalServerError(err.Error())
+	}
+	item := &db.Media{
+		Id:          id,
+		Hash:        hash,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Caption:     caption,
+	}
+	normalizeMedia(item)
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumbnail, size, err := media.Thumbnail(content)
+		if goerrors.Is(err, media.ErrImageTooLarge) {
+			return nil, false, errors.NewUnprocessableEntityError(err.Error())
+		}
+		if err != nil {
+			return nil, false, errors.NewUnprocessableEntityError(fmt.Sprintf("invalid image: %v", err))
+		}
+		if err := s.store.SaveThumbnail(hash, thumbnail); err != nil {
+			return nil, false, errors.NewInternalServerError(err.Error())
+		}
+		width, height := int32(size.X), int32(size.Y)
+		item.Width, item.Height, item.HasThumbnail = &width, &height, true
+	}
+	if err := s.store.Save(hash, content); err != nil {
+		return nil, false, errors.NewInternalServerError(err.Error())
+	}
+
+	// The same content may have been uploaded concurrently, so the hash is checked again within the transaction
+	var created *db.Media
+	err = s.transaction(func(tx *MediaService) error {
+		existing, err = db.GetMediaByHash(tx.pool, hash)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if existing != nil {
+			return nil
+		}
+
+		created, err = db.CreateMedia(tx.pool, item)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_MEDIA, created.Id.String(), nil, created)
+
+	return created, true, nil
+}
+
+func (s *MediaService) GetMedia(id uuid.UUID, viewer Viewer) (*db.Media, error) {
+	item, err := s.getMedia(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMediaVisible(item, viewer); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// OpenMediaFile opens the file or its thumbnail for serving, where the caller has to close it
+func (s *MediaService) OpenMediaFile(id uuid.UUID, thumbnail bool, viewer Viewer) (*db.Media, *os.File, error) {
+	item, err := s.GetMedia(id, viewer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if thumbnail && !item.HasThumbnail {
+		return nil, nil, errors.NewNotFoundError(fmt.Sprintf("Media '%s' has no thumbnail", id))
+	}
+
+	open := s.store.Open
+	if thumbnail {
+		open = s.store.OpenThumbnail
+	}
+	file, err := open(item.Hash)
+	if err != nil {
+		return nil, nil, errors.NewInternalServerError(err.Error())
+	}
+	return item, file, nil
+}
+
+// GetPersonMedia returns the media attached to the person itself, which living persons only show to viewers who
+// may see their details, as a portrait identifies them just as well as their name
+func (s *MediaService) GetPersonMedia(personId uuid.UUID, viewer Viewer) ([]*db.Media, error) {
+	if _, err := s.getVisiblePerson(personId, viewer); err != nil {
+		return nil, err
+	}
+
+	items, err := db.GetMediaByPersonId(s.pool, personId)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	return items, nil
+}
+
+func (s *MediaService) GetEventMedia(eventId uuid.UUID, viewer Viewer) ([]*db.Media, error) {
+	event, err := s.getEvent(eventId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getVisiblePerson(event.PersonId, viewer); err != nil {
+		return nil, err
+	}
+
+	items, err := db.GetMediaByEventId(s.pool, eventId)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	return items, nil
+}
+
+// UpdateMedia applies the JSON patch just like UpdatePerson, but only the file name and the caption can be changed
+func (s *MediaService) UpdateMedia(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Media, error) {
+	var before json.RawMessage
+	var updated *db.Media
+	err := s.transaction(func(tx *MediaService) error {
+		item, err := tx.getMedia(id)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(item))
+
+		patched := *item
+		if err := json.Unmarshal(patch, &patched); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		item.FileName, item.Caption = filepath.Base(strings.TrimSpace(patched.FileName)), patched.Caption
+
+		normalizeMedia(item)
+		if len(item.FileName) == 0 || item.FileName == "." {
+			return errors.NewUnprocessableEntityError("FileName must not be empty")
+		}
+
+		updated, err = db.UpdateMedia(tx.pool, item)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_MEDIA, id.String(), before, updated)
+
+	return updated, nil
+}
+
+// DeleteMedia also deletes the file, which no other media can refer to, as every content is only stored once
+func (s *MediaService) DeleteMedia(id uuid.UUID, actingUsername string) error {
+	var item *db.Media
+	err := s.transaction(func(tx *MediaService) error {
+		var err error
+		item, err = tx.getMedia(id)
+		if err != nil {
+			return err
+		}
+
+		if err := db.DeleteMedia(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(item.Hash); err != nil {
+		// The media is gone already, so a leftover file is only logged instead of failing the deletion
+		log.Printf("[media] Failed to delete file '%s': %v", item.Hash, err)
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_MEDIA, id.String(), item, nil)
+
+	return nil
+}
+
+func (s *MediaService) AttachMediaToPerson(id, personId uuid.UUID, actingUsername string) error {
+	err := s.transaction(func(tx *MediaService) error {
+		if _, err := tx.getMedia(id); err != nil {
+			return err
+		}
+		if _, err := tx.getPerson(personId); err != nil {
+			return err
+		}
+
+		if err := db.AttachMediaToPerson(tx.pool, id, personId); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_MEDIA_ATTACHMENT,
+		auditEntityId(personId.String(), id.String()), nil, nil)
+
+	return nil
+}
+
+func (s *MediaService) DetachMediaFromPerson(id, personId uuid.UUID, actingUsername string) error {
+	err := s.transaction(func(tx *MediaService) error {
+		items, err := db.GetMediaByPersonId(tx.pool, personId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if !lo.ContainsBy(items, func(item *db.Media) bool { return item.Id == id }) {
+			return errors.NewNotFoundError(fmt.Sprintf("Media '%s' is not attached to '%s'", id, personId))
+		}
+
+		if err := db.DetachMediaFromPerson(tx.pool, id, personId); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_MEDIA_ATTACHMENT,
+		auditEntityId(personId.String(), id.String()), nil, nil)
+
+	return nil
+}
+
+func (s *MediaService) AttachMediaToEvent(id, eventId uuid.UUID, actingUsername string) error {
+	err := s.transaction(func(tx *MediaService) error {
+		if _, err := tx.getMedia(id); err != nil {
+			return err
+		}
+		if _, err := tx.getEvent(eventId); err != nil {
+			return err
+		}
+
+		if err := db.AttachMediaToEvent(tx.pool, id, eventId); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_MEDIA_ATTACHMENT,
+		auditEntityId(eventId.String(), id.String()), nil, nil)
+
+	return nil
+}
+
+func (s *MediaService) DetachMediaFromEvent(id, eventId uuid.UUID, actingUsername string) error {
+	err := s.transaction(func(tx *MediaService) error {
+		items, err := db.GetMediaByEventId(tx.pool, eventId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if !lo.ContainsBy(items, func(item *db.Media) bool { return item.Id == id }) {
+			return errors.NewNotFoundError(fmt.Sprintf("Media '%s' is not attached to '%s'", id, eventId))
+		}
+
+		if err := db.DetachMediaFromEvent(tx.pool, id, eventId); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_MEDIA_ATTACHMENT,
+		auditEntityId(eventId.String(), id.String()), nil, nil)
+
+	return nil
+}
+
+// checkMediaVisible allows the media if any of the persons it is attached to may be seen in detail by the viewer.
+// Media which is not attached to anyone yet is only visible to unrestricted viewers.
+func (s *MediaService) checkMediaVisible(item *db.Media, viewer Viewer) error {
+	visible, err := s.access.visiblePersons(viewer)
+	if err != nil {
+		return err
+	}
+	redact := s.access.mustRedact(viewer)
+	if visible == nil && !redact {
+		return nil
+	}
+
+	persons, err := db.GetPersonsOfMedia(s.pool, item.Id)
+	if err != nil {
+		return kuzuError(err)
+	}
+	for _, person := range persons {
+		if checkVisible(visible, person.Id) == nil && !(redact && isLiving(person)) {
+			return nil
+		}
+	}
+	return errors.NewForbiddenError(fmt.Sprintf("Media '%s' is outside of your visible family tree", item.Id))
+}
+
+// getVisiblePerson checks the visibility before loading the person, so that hidden persons cannot be told from unknown
+// ones, while living persons can only be told apart once they are loaded
+func (s *MediaService) getVisiblePerson(id uuid.UUID, viewer Viewer) (*db.Person, error) {
+	visible, err := s.access.visiblePersons(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVisible(visible, id); err != nil {
+		return nil, err
+	}
+	person, err := s.getPerson(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.access.mustRedact(viewer) && isLiving(person) {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("'%s' is a living person", person.Id))
+	}
+	return person, nil
+}
+
+// transaction runs fn on a copy of the service within one write transaction, just like the one of FamilyTreeService
+func (s *MediaService) transaction(fn func(tx *MediaService) error) error {
+	var fnErr error
+	err := s.pool.Transaction(func(pool *db.KuzuPool) error {
+		tx := *s
+		tx.pool = pool
+		fnErr = fn(&tx)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return kuzuError(err)
+	}
+	return nil
+}
+
+func (s *MediaService) getMedia(id uuid.UUID) (*db.Media, error) {
+	item, err := db.GetMediaById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if item == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Media '%s' not found", id))
+	}
+	return item, nil
+}
+
+func (s *MediaService) getPerson(id uuid.UUID) (*db.Person, error) {
+	person, err := db.GetPersonById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if person == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("'%s' not found", id))
+	}
+	return person, nil
+}
+
+func (s *MediaService) getEvent(id uuid.UUID) (*db.Event, error) {
+	event, err := db.GetEventById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if event == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Event '%s' not found", id))
+	}
+	return event, nil
+}
+
+// normalizeMedia treats an empty caption as missing
+func normalizeMedia(item *db.Media) {
+	if item.Caption == nil {
+		return
+	}
+	trimmed := strings.TrimSpace(*item.Caption)
+	if len(trimmed) == 0 {
+		item.Caption = nil
+	} else {
+		item.Caption = &trimmed
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+var validGenders = map[string]bool{"m": true, "f": true}
+
+var validDateQualifiers = map[string]bool{
+	dates.QUALIFIER_ABOUT: true, dates.QUALIFIER_BEFORE: true, dates.QUALIFIER_AFTER: true, dates.QUALIFIER_BETWEEN: true,
+}
+
+var validCalendars = map[string]bool{dates.CALENDAR_GREGORIAN: true, dates.CALENDAR_JULIAN: true}
+
+func (s *FamilyTreeService) CreatePerson(req *PostPersonRequest, actingUsername string) (*db.Person, error) {
+	person := req.Person
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	person.Id = id
+
+	return s.createPerson(&person, actingUsername)
+}
+
+// createPerson keeps the id of the person, which allows deleted persons to be restored
+func (s *FamilyTreeService) createPerson(person *db.Person, actingUsername string) (*db.Person, error) {
+	normalizePerson(person)
+	if err := validatePerson(person); err != nil {
+		return nil, err
+	}
+
+	created, err := db.CreatePerson(s.pool, person)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_PERSON, created.Id.String(), nil, created)
+
+	return created, nil
+}
+
+// UpdatePerson applies the JSON patch onto the stored person, so that absent fields remain unchanged
+// while fields explicitly set to null are cleared
+func (s *FamilyTreeService) UpdatePerson(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Person, error) {
+	var before json.RawMessage
+	var updated *db.Person
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		person, err := tx.getPerson(id)
+		if err != nil {
+			return err
+		}
+		// Unmarshalling writes through the pointers of the stored person, so the previous values have to be copied first
+		before = json.RawMessage(marshalAuditValue(person))
+
+		if err := json.Unmarshal(patch, person); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		person.Id = id
+
+		normalizePerson(person)
+		if err := validatePerson(person); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdatePerson(tx.pool, person)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_PERSON, id.String(), before, updated)
+
+	return updated, nil
+}
+
+// DeletePerson also records the deletion of all parent and marriage relations of the person, which are removed
+// together with it
+func (s *FamilyTreeService) DeletePerson(id uuid.UUID, actingUsername string) error {
+	var person *db.Person
+	var parents, children []*db.ParentRelation
+	var marriages []*db.MarriageRelation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		person, err = tx.getPerson(id)
+		if err != nil {
+			return err
+		}
+
+		if parents, err = db.GetParentRelationsByChildId(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		if children, err = db.GetParentRelationsByParentId(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		if marriages, err = db.GetMarriageRelationsByPersonId(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+
+		if err := db.DeletePerson(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+
+		// The children lost a parent, which may turn full siblings into half siblings
+		childIds := lo.Map(children, func(item *db.ParentRelation, index int) uuid.UUID {
+			return item.ChildId
+		})
+		return tx.recomputeSiblingRelations(childIds...)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, relation := range append(parents, children...) {
+		s.recordChange(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PARENT_RELATION,
+			auditEntityId(relation.ParentId.String(), relation.ChildId.String()), relation, nil)
+	}
+	for _, relation := range marriages {
+		s.recordChange(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_MARRIAGE_RELATION,
+			marriageEntityId(relation.Person1Id, relation.Person2Id), relation, nil)
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PERSON, id.String(), person, nil)
+
+	return nil
+}
+
+func (s *FamilyTreeService) getPerson(id uuid.UUID) (*db.Person, error) {
+	person, err := db.GetPersonById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if person == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("'%s' not found", id))
+	}
+	return person, nil
+}
+
+// normalizePerson trims all names and treats empty strings as missing values
+func normalizePerson(person *db.Person) {
+	for _, field := range []**string{
+		&person.FirstName, &person.MiddleName, &person.LastName, &person.BirthName, &person.Gender,
+		&person.BirthDateQualifier, &person.BirthDateCalendar, &person.DeathDateQualifier, &person.DeathDateCalendar,
+	} {
+		if *field == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(**field)
+		if len(trimmed) == 0 {
+			*field = nil
+		} else {
+			*field = &trimmed
+		}
+	}
+	for _, field := range []**string{
+		&person.Gender, &person.BirthDateQualifier, &person.BirthDateCalendar, &person.DeathDateQualifier,
+		&person.DeathDateCalendar,
+	} {
+		if *field != nil {
+			lower := strings.ToLower(**field)
+			*field = &lower
+		}
+	}
+}
+
+func validatePerson(person *db.Person) error {
+	if person.FirstName == nil && person.LastName == nil {
+		return errors.NewUnprocessableEntityError("either FirstName or LastName is required")
+	}
+	if person.Gender != nil && !validGenders[*person.Gender] {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid Gender '%s'", *person.Gender))
+	}
+
+	if err := validateDate("BirthDate", person.BirthDate()); err != nil {
+		return err
+	}
+	if err := validateDate("DeathDate", person.DeathDate()); err != nil {
+		return err
+	}
+
+	hasDeathDate := person.DeathDateYear != nil || person.DeathDateMonth != nil || person.DeathDateDay != nil
+	if hasDeathDate && person.IsDead != nil && !*person.IsDead {
+		return errors.NewUnprocessableEntityError("DeathDate requires IsDead to not be false")
+	}
+	// Uncertain dates are only rejected if they cannot overlap at all
+	birthEarliest, _ := person.BirthDate().Bounds()
+	_, deathLatest := person.DeathDate().Bounds()
+	if birthEarliest != nil && deathLatest != nil && deathLatest.Before(*birthEarliest) {
+		return errors.NewUnprocessableEntityError("DeathDate must not be before BirthDate")
+	}
+
+	return nil
+}
+
+func validateDate(name string, date dates.Date) error {
+	if date.Qualifier != nil && !validDateQualifiers[*date.Qualifier] {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid %sQualifier '%s'", name, *date.Qualifier))
+	}
+	if date.Calendar != nil && !validCalendars[*date.Calendar] {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid %sCalendar '%s'", name, *date.Calendar))
+	}
+	if (date.Qualifier != nil || date.Calendar != nil) && date.Year == nil {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("%sQualifier and %sCalendar require %sYear", name, name, name))
+	}
+	if err := validateCalendarDate(name, date.Calendar, date.Year, date.Month, date.Day); err != nil {
+		return err
+	}
+
+	isBetween := date.Qualifier != nil && *date.Qualifier == dates.QUALIFIER_BETWEEN
+	hasTo := date.ToYear != nil || date.ToMonth != nil || date.ToDay != nil
+	if hasTo && !isBetween {
+		return errors.NewUnprocessableEntityError(
+			fmt.Sprintf("%sTo requires %sQualifier '%s'", name, name, dates.QUALIFIER_BETWEEN))
+	}
+	if !isBetween {
+		return nil
+	}
+	if date.ToYear == nil {
+		return errors.NewUnprocessableEntityError(
+			fmt.Sprintf("%sQualifier '%s' requires %sToYear", name, dates.QUALIFIER_BETWEEN, name))
+	}
+	if err := validateCalendarDate(name+"To", date.Calendar, date.ToYear, date.ToMonth, date.ToDay); err != nil {
+		return err
+	}
+	if earliest, latest := date.Bounds(); latest.Before(*earliest) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("%sTo must not be before %s", name, name))
+	}
+	return nil
+}
+
+func validatePartialDate(name string, year, month, day *int32) error {
+	return validateCalendarDate(name, nil, year, month, day)
+}
+
+func validateCalendarDate(name string, calendar *string, year, month, day *int32) error {
+	if day != nil && month == nil {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("%sDay requires %sMonth", name, name))
+	}
+	if year != nil && (*year < 1 || int(*year) > time.Now().Year()) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid %sYear %d", name, *year))
+	}
+	if month != nil && (*month < 1 || *month > 12) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid %sMonth %d", name, *month))
+	}
+	if day != nil {
+		// Without a year, February is allowed to have 29 days
+		refYear := int32(2000)
+		if year != nil {
+			refYear = *year
+		}
+		if *day < 1 || *day > dates.DaysInMonth(calendar, refYear, *month) {
+			return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid %sDay %d", name, *day))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// Matches on historical names are ranked below matches on the current name
+const weightHistoricalName = 0.9
+
+func (s *FamilyTreeService) GetPlace(id uuid.UUID) (*PlaceDto, error) {
+	place, err := s.getPlace(id)
+	if err != nil {
+		return nil, err
+	}
+
+	places, err := s.getPlacesById()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlaceDto{Place: place, Path: placePath(places, place)}, nil
+}
+
+// SearchPlaces ranks the places by how well the query matches their current or historical names, just like
+// SearchPersons. Every place is returned only once, no matter how many of its spellings match.
+func (s *FamilyTreeService) SearchPlaces(query string, limit int) ([]*PlaceSearchResultDto, error) {
+	terms := nameVariants(query)
+	if len(terms) == 0 {
+		return nil, errors.NewBadRequestError("query must not be empty")
+	}
+
+	places, err := s.getPlacesById()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*PlaceSearchResultDto, 0)
+	for _, place := range places {
+		result := &PlaceSearchResultDto{
+			Id:        place.Id,
+			Name:      place.Name,
+			Path:      placePath(places, place),
+			Latitude:  place.Latitude,
+			Longitude: place.Longitude,
+		}
+
+		for i, name := range append([]string{place.Name}, place.HistoricalNames...) {
+			weight := 1.0
+			if i > 0 {
+				weight = weightHistoricalName
+			}
+			nameParts := lo.Map(nameVariants(name), func(item []string, index int) weightedNamePart {
+				return weightedNamePart{item, weight}
+			})
+
+			if score, ok := scoreTerms(terms, nameParts); ok && score > result.Score {
+				result.Score = score
+				result.MatchedName = nil
+				if i > 0 {
+					result.MatchedName = &name
+				}
+			}
+		}
+
+		if result.Score > 0 {
+			results = append(results, result)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b *PlaceSearchResultDto) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// GetPlacePersons returns the persons with events at the place or anywhere within it, together with these events.
+// Just like for the search, living persons are skipped for viewers who may not see them.
+func (s *FamilyTreeService) GetPlacePersons(id uuid.UUID, viewer Viewer) ([]*PlacePersonDto, error) {
+	if _, err := s.getPlace(id); err != nil {
+		return nil, err
+	}
+
+	persons, err := db.GetPersonsAtPlace(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	events, err := db.GetEventsAtPlace(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+
+	visible, err := s.access.visiblePersons(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	redact := s.access.mustRedact(viewer)
+	results := make(map[uuid.UUID]*PlacePersonDto)
+	for _, person := range persons {
+		if checkVisible(visible, person.Id) != nil || (redact && isLiving(person)) {
+			continue
+		}
+		results[person.Id] = &PlacePersonDto{
+			Id:            person.Id,
+			FirstName:     person.FirstName,
+			MiddleName:    person.MiddleName,
+			LastName:      person.LastName,
+			BirthName:     person.BirthName,
+			BirthDateYear: person.BirthDateYear,
+			DeathDateYear: person.DeathDateYear,
+			Events:        make([]*db.Event, 0),
+		}
+	}
+	for _, event := range events {
+		if result, ok := results[event.PersonId]; ok {
+			result.Events = append(result.Events, event)
+		}
+	}
+
+	sorted := lo.Values(results)
+	for _, result := range sorted {
+		slices.SortFunc(result.Events, compareEvents)
+	}
+	slices.SortFunc(sorted, func(a, b *PlacePersonDto) int {
+		if c := cmp.Compare(derefString(a.LastName), derefString(b.LastName)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(derefString(a.FirstName), derefString(b.FirstName)); c != 0 {
+			return c
+		}
+		return cmp.Compare(derefDateInt32(a.BirthDateYear), derefDateInt32(b.BirthDateYear))
+	})
+
+	return sorted, nil
+}
+
+func (s *FamilyTreeService) CreatePlace(req *PostPlaceRequest, actingUsername string) (*db.Place, error) {
+	place := req.Place
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	place.Id = id
+
+	normalizePlace(&place)
+
+	// The uniqueness of the name is checked within the transaction, so that it still holds when the place is created
+	var created *db.Place
+	err = s.transaction(func(tx *FamilyTreeService) error {
+		if err := tx.validatePlace(&place); err != nil {
+			return err
+		}
+
+		created, err = db.CreatePlace(tx.pool, &place)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_PLACE, created.Id.String(), nil, created)
+
+	return created, nil
+}
+
+// UpdatePlace applies the JSON patch just like UpdatePerson, where a null ParentId moves the place to the top level
+func (s *FamilyTreeService) UpdatePlace(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Place, error) {
+	var before json.RawMessage
+	var updated *db.Place
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		place, err := tx.getPlace(id)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(place))
+
+		if err := json.Unmarshal(patch, place); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		place.Id = id
+
+		normalizePlace(place)
+		if err := tx.validatePlace(place); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdatePlace(tx.pool, place)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_PLACE, id.String(), before, updated)
+
+	return updated, nil
+}
+
+// DeletePlace refuses to delete places which are still referenced, as the references would silently get lost
+func (s *FamilyTreeService) DeletePlace(id uuid.UUID, actingUsername string) error {
+	var place *db.Place
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		place, err = tx.getPlace(id)
+		if err != nil {
+			return err
+		}
+
+		children, err := db.GetPlacesByParentId(tx.pool, id)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if len(children) > 0 {
+			return errors.NewConflictError(fmt.Sprintf("'%s' still contains %d places", id, len(children)))
+		}
+		events, err := db.GetEventsAtPlace(tx.pool, id)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if len(events) > 0 {
+			return errors.NewConflictError(fmt.Sprintf("'%s' is still the place of %d events", id, len(events)))
+		}
+
+		if err := db.DeletePlace(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PLACE, id.String(), place, nil)
+
+	return nil
+}
+
+// MergePlace deduplicates places which turned out to be the same, e.g. because they were recorded under different
+// spellings. The duplicate becomes a historical name of the place, and all its events and sub-places move over. The
+// whole merge is one transaction, so that it neither ends halfway nor works on a hierarchy which changed meanwhile.
+func (s *FamilyTreeService) MergePlace(id, duplicateId uuid.UUID, actingUsername string) (*db.Place, error) {
+	if id == duplicateId {
+		return nil, errors.NewUnprocessableEntityError("a place cannot be merged with itself")
+	}
+
+	var duplicate, updated *db.Place
+	var before json.RawMessage
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		place, err := tx.getPlace(id)
+		if err != nil {
+			return err
+		}
+		duplicate, err = tx.getPlace(duplicateId)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(place))
+
+		place.HistoricalNames = append(place.HistoricalNames, duplicate.Name)
+		place.HistoricalNames = append(place.HistoricalNames, duplicate.HistoricalNames...)
+		if place.Latitude == nil && place.Longitude == nil {
+			place.Latitude, place.Longitude = duplicate.Latitude, duplicate.Longitude
+		}
+		// The sub-places of the duplicate move to the place, so the place must not stay located within the duplicate
+		places, err := tx.getPlacesById()
+		if err != nil {
+			return err
+		}
+		if isLocatedWithin(places, place, duplicateId) {
+			place.ParentId = duplicate.ParentId
+		}
+		normalizePlace(place)
+
+		if err := db.MovePlaceReferences(tx.pool, duplicateId, id); err != nil {
+			return kuzuError(err)
+		}
+		if err := db.DeletePlace(tx.pool, duplicateId); err != nil {
+			return kuzuError(err)
+		}
+		updated, err = db.UpdatePlace(tx.pool, place)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PLACE, duplicateId.String(), duplicate, nil)
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_PLACE, id.String(), before, updated)
+
+	return updated, nil
+}
+
+func (s *FamilyTreeService) getPlace(id uuid.UUID) (*db.Place, error) {
+	place, err := db.GetPlaceById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if place == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Place '%s' not found", id))
+	}
+	return place, nil
+}
+
+func (s *FamilyTreeService) getPlacesById() (map[uuid.UUID]*db.Place, error) {
+	places, err := db.GetAllPlaces(s.pool)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	return lo.KeyBy(places, func(item *db.Place) uuid.UUID {
+		return item.Id
+	}), nil
+}
+
+// placePath lists the names of the places the place is located within, from the innermost to the outermost
+func placePath(places map[uuid.UUID]*db.Place, place *db.Place) []string {
+	path := make([]string, 0)
+	for parentId := place.ParentId; parentId != nil; {
+		parent, ok := places[*parentId]
+		// The hierarchy is validated to be free of cycles, but a corrupted one must not loop forever
+		if !ok || len(path) > len(places) {
+			break
+		}
+		path = append(path, parent.Name)
+		parentId = parent.ParentId
+	}
+	return path
+}
+
+// isLocatedWithin checks whether the other place is anywhere above the place in the hierarchy
+func isLocatedWithin(places map[uuid.UUID]*db.Place, place *db.Place, otherId uuid.UUID) bool {
+	visited := make(map[uuid.UUID]bool)
+	for parentId := place.ParentId; parentId != nil && !visited[*parentId]; {
+		if *parentId == otherId {
+			return true
+		}
+		visited[*parentId] = true
+		parent, ok := places[*parentId]
+		if !ok {
+			return false
+		}
+		parentId = parent.ParentId
+	}
+	return false
+}
+
+// placeNameKey identifies all spellings of a name which only differ in case, diacritics or umlaut transliterations
+func placeNameKey(name string) string {
+	return strings.Join(strings.Fields(normalizeName(name)), " ")
+}
+
+// normalizePlace trims the names and drops historical names which are empty or only another spelling of a known name
+func normalizePlace(place *db.Place) {
+	place.Name = strings.TrimSpace(place.Name)
+
+	known := map[string]bool{placeNameKey(place.Name): true}
+	historicalNames := make([]string, 0)
+	for _, name := range place.HistoricalNames {
+		name = strings.TrimSpace(name)
+		key := placeNameKey(name)
+		if len(key) == 0 || known[key] {
+			continue
+		}
+		known[key] = true
+		historicalNames = append(historicalNames, name)
+	}
+	place.HistoricalNames = historicalNames
+}
+
+func (s *FamilyTreeService) validatePlace(place *db.Place) error {
+	if len(placeNameKey(place.Name)) == 0 {
+		return errors.NewUnprocessableEntityError("Name must not be empty")
+	}
+	if place.Latitude != nil && (*place.Latitude < -90 || *place.Latitude > 90) {
+		return errors.NewUnprocessableEntityError("Latitude must be between -90 and 90")
+	}
+	if place.Longitude != nil && (*place.Longitude < -180 || *place.Longitude > 180) {
+		return errors.NewUnprocessableEntityError("Longitude must be between -180 and 180")
+	}
+
+	places, err := s.getPlacesById()
+	if err != nil {
+		return err
+	}
+
+	if place.ParentId != nil {
+		if _, ok := places[*place.ParentId]; !ok {
+			return errors.NewUnprocessableEntityError(fmt.Sprintf("ParentId '%s' is not a known place", *place.ParentId))
+		}
+		if *place.ParentId == place.Id || isLocatedWithin(places, places[*place.ParentId], place.Id) {
+			return errors.NewUnprocessableEntityError("a place cannot be located within itself")
+		}
+	}
+
+	// Spellings of the same place under the same parent are the same place, which has to be merged instead
+	keys := lo.Map(append([]string{place.Name}, place.HistoricalNames...), func(item string, index int) string {
+		return placeNameKey(item)
+	})
+	for _, other := range places {
+		if other.Id == place.Id || lo.FromPtr(other.ParentId) != lo.FromPtr(place.ParentId) {
+			continue
+		}
+		for _, name := range append([]string{other.Name}, other.HistoricalNames...) {
+			if lo.Contains(keys, placeNameKey(name)) {
+				return errors.NewConflictError(fmt.Sprintf("'%s' is already known as place '%s'", name, other.Id))
+			}
+		}
+	}
+
+	return nil
+}
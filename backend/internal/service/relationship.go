@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+)
+
+const maxParentsPerChild = 2
+
+func (s *FamilyTreeService) AddParentRelation(relation *db.ParentRelation, actingUsername string) (*db.ParentRelation, error) {
+	if relation.ParentId == relation.ChildId {
+		return nil, errors.NewUnprocessableEntityError("a person cannot be their own parent")
+	}
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		if err := tx.ensurePersonsExist(relation.ParentId, relation.ChildId); err != nil {
+			return err
+		}
+
+		parents, err := db.GetParentRelationsByChildId(tx.pool, relation.ChildId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		for _, parent := range parents {
+			if parent.ParentId == relation.ParentId {
+				return errors.NewConflictError(fmt.Sprintf("'%s' is already a parent of '%s'", relation.ParentId, relation.ChildId))
+			}
+		}
+		if len(parents) >= maxParentsPerChild {
+			return errors.NewConflictError(fmt.Sprintf("'%s' already has %d parents", relation.ChildId, maxParentsPerChild))
+		}
+
+		if err := db.CreateParentRelation(tx.pool, relation); err != nil {
+			return kuzuError(err)
+		}
+		return tx.recomputeSiblingRelations(relation.ChildId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_PARENT_RELATION,
+		auditEntityId(relation.ParentId.String(), relation.ChildId.String()), nil, relation)
+
+	return relation, nil
+}
+
+func (s *FamilyTreeService) RemoveParentRelation(parentId, childId uuid.UUID, actingUsername string) error {
+	var relation *db.ParentRelation
+	subjectId := auditEntityId(parentId.String(), childId.String())
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		relation, err = db.GetParentRelation(tx.pool, parentId, childId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if relation == nil {
+			return errors.NewNotFoundError(fmt.Sprintf("'%s' is not a parent of '%s'", parentId, childId))
+		}
+
+		if err := db.DeleteParentRelation(tx.pool, parentId, childId); err != nil {
+			return kuzuError(err)
+		}
+		if err := db.DeleteCitationsOfSubject(tx.pool, AUDIT_ENTITY_PARENT_RELATION, subjectId); err != nil {
+			return kuzuError(err)
+		}
+		return tx.recomputeSiblingRelations(childId)
+	})
+	if err != nil {
+		return err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_PARENT_RELATION, subjectId, relation, nil)
+
+	return nil
+}
+
+func (s *FamilyTreeService) AddMarriageRelation(relation *db.MarriageRelation, actingUsername string) (*db.MarriageRelation, error) {
+	if relation.Person1Id == relation.Person2Id {
+		return nil, errors.NewUnprocessableEntityError("a person cannot be married to themselves")
+	}
+	if err := validateMarriageRelation(relation); err != nil {
+		return nil, err
+	}
+	var created *db.MarriageRelation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		if err := tx.ensurePersonsExist(relation.Person1Id, relation.Person2Id); err != nil {
+			return err
+		}
+
+		existing, err := db.GetMarriageRelation(tx.pool, relation.Person1Id, relation.Person2Id)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if existing != nil {
+			return errors.NewConflictError(fmt.Sprintf("'%s' and '%s' are already married", relation.Person1Id, relation.Person2Id))
+		}
+
+		created, err = db.CreateMarriageRelation(tx.pool, relation)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_MARRIAGE_RELATION,
+		marriageEntityId(created.Person1Id, created.Person2Id), nil, created)
+
+	return created, nil
+}
+
+func (s *FamilyTreeService) EndMarriageRelation(person1Id, person2Id uuid.UUID, req *PatchMarriageEndRequest, actingUsername string) (*db.MarriageRelation, error) {
+	return s.updateMarriageRelation(person1Id, person2Id, func(relation *db.MarriageRelation) {
+		relation.UntilYear = req.UntilYear
+		relation.UntilMonth = req.UntilMonth
+		relation.UntilDay = req.UntilDay
+	}, actingUsername)
+}
+
+// updateMarriageRelation applies the update to a copy of the stored relation within one transaction, so that
+// concurrent updates cannot overwrite each other. The update must keep the direction of the stored edge.
+func (s *FamilyTreeService) updateMarriageRelation(person1Id, person2Id uuid.UUID, update func(relation *db.MarriageRelation), actingUsername string) (*db.MarriageRelation, error) {
+	var existing, updated *db.MarriageRelation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		existing, err = tx.getMarriageRelation(person1Id, person2Id)
+		if err != nil {
+			return err
+		}
+
+		relation := *existing
+		update(&relation)
+		if err := validateMarriageRelation(&relation); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdateMarriageRelation(tx.pool, &relation)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_MARRIAGE_RELATION,
+		marriageEntityId(updated.Person1Id, updated.Person2Id), existing, updated)
+
+	return updated, nil
+}
+
+func (s *FamilyTreeService) RemoveMarriageRelation(person1Id, person2Id uuid.UUID, actingUsername string) error {
+	var relation *db.MarriageRelation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		relation, err = tx.getMarriageRelation(person1Id, person2Id)
+		if err != nil {
+			return err
+		}
+
+		if err := db.DeleteMarriageRelation(tx.pool, relation.Person1Id, relation.Person2Id); err != nil {
+			return kuzuError(err)
+		}
+		subjectId := marriageEntityId(relation.Person1Id, relation.Person2Id)
+		if err := db.DeleteCitationsOfSubject(tx.pool, AUDIT_ENTITY_MARRIAGE_RELATION, subjectId); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.recordChange(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_MARRIAGE_RELATION,
+		marriageEntityId(relation.Person1Id, relation.Person2Id), relation, nil)
+
+	return nil
+}
+
+// RebuildSiblingRelations derives all sibling relations from scratch, e.g. after data was changed outside the API
+func (s *FamilyTreeService) RebuildSiblingRelations(actingUsername string) error {
+	if err := db.RebuildAllSiblingRelations(s.pool); err != nil {
+		return kuzuError(err)
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_REBUILD, AUDIT_ENTITY_SIBLING_RELATIONS, "", nil, nil)
+	return nil
+}
+
+// recomputeSiblingRelations has to be called for every child whose parents have changed,
+// as the derived IS_SIBLING edges would become stale otherwise
+func (s *FamilyTreeService) recomputeSiblingRelations(childIds ...uuid.UUID) error {
+	for _, childId := range childIds {
+		if err := db.RecomputeSiblingRelations(s.pool, childId); err != nil {
+			return kuzuError(err)
+		}
+	}
+	return nil
+}
+
+func (s *FamilyTreeService) getMarriageRelation(person1Id, person2Id uuid.UUID) (*db.MarriageRelation, error) {
+	relation, err := db.GetMarriageRelation(s.pool, person1Id, person2Id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if relation == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("'%s' and '%s' are not married", person1Id, person2Id))
+	}
+	return relation, nil
+}
+
+func (s *FamilyTreeService) ensurePersonsExist(ids ...uuid.UUID) error {
+	for _, id := range ids {
+		if _, err := s.getPerson(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMarriageRelation(relation *db.MarriageRelation) error {
+	if err := validatePartialDate("Since", relation.SinceYear, relation.SinceMonth, relation.SinceDay); err != nil {
+		return err
+	}
+	if err := validatePartialDate("Until", relation.UntilYear, relation.UntilMonth, relation.UntilDay); err != nil {
+		return err
+	}
+	if relation.SinceYear != nil && relation.UntilYear != nil && *relation.UntilYear < *relation.SinceYear {
+		return errors.NewUnprocessableEntityError("Until must not be before Since")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	scoreExact  = 1.0
+	scorePrefix = 0.9
+	scoreTypo   = 0.8
+	// Every edit reduces the score of a typo match by this amount
+	scorePerEdit = 0.15
+	// Matches on middle or birth names are ranked below matches on the primary names
+	weightSecondaryName = 0.9
+	// Matches relying on the folded spelling of umlauts are ranked below matches on the name as written
+	weightTransliteration = 0.95
+)
+
+type weightedNamePart struct {
+	variants []string
+	weight   float64
+}
+
+// umlautTransliterations folds the spelling of umlauts without diacritics, e.g. "Mueller" for "Müller". As "ae",
+// "oe" and "ue" are just as common in names without umlauts, e.g. "Michael", the folded spelling is only an
+// alternative to the name as written.
+var umlautTransliterations = strings.NewReplacer("ae", "a", "oe", "o", "ue", "u")
+
+// SearchPersons ranks all persons by how well the query matches their names. Every term of the query has to match
+// a name part, where typos, prefixes and diacritic or umlaut variants are tolerated. Invisible persons are skipped,
+// just like living persons for viewers who may not see them, as even a redacted match would reveal the name.
+func (s *FamilyTreeService) SearchPersons(query string, limit int, viewer Viewer) ([]*PersonSearchResultDto, error) {
+	terms := nameVariants(query)
+	if len(terms) == 0 {
+		return nil, errors.NewBadRequestError("query must not be empty")
+	}
+
+	persons, err := db.GetAllPersons(s.pool)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+
+	visible, err := s.access.visiblePersons(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	redact := s.access.mustRedact(viewer)
+	results := make([]*PersonSearchResultDto, 0)
+	for _, person := range persons {
+		if checkVisible(visible, person.Id) != nil || (redact && isLiving(person)) {
+			continue
+		}
+
+		nameParts := make([]weightedNamePart, 0)
+		for i, name := range []*string{person.FirstName, person.LastName, person.MiddleName, person.BirthName} {
+			if name == nil {
+				continue
+			}
+			weight := 1.0
+			if i >= 2 {
+				weight = weightSecondaryName
+			}
+			for _, variants := range nameVariants(*name) {
+				nameParts = append(nameParts, weightedNamePart{variants, weight})
+			}
+		}
+
+		score, ok := scoreTerms(terms, nameParts)
+		if !ok {
+			continue
+		}
+
+		results = append(results, &PersonSearchResultDto{
+			Id:            person.Id,
+			FirstName:     person.FirstName,
+			MiddleName:    person.MiddleName,
+			LastName:      person.LastName,
+			BirthName:     person.BirthName,
+			BirthDateYear: person.BirthDateYear,
+			DeathDateYear: person.DeathDateYear,
+			Score:         score,
+		})
+	}
+
+	slices.SortFunc(results, func(a, b *PersonSearchResultDto) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		if c := cmp.Compare(derefString(a.LastName), derefString(b.LastName)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(derefString(a.FirstName), derefString(b.FirstName)); c != 0 {
+			return c
+		}
+		return cmp.Compare(derefDateInt32(a.BirthDateYear), derefDateInt32(b.BirthDateYear))
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// scoreTerms averages the best score of every term over all spelling variants, but fails if any term does not match
+// at all
+func scoreTerms(terms [][]string, nameParts []weightedNamePart) (float64, bool) {
+	total := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for _, part := range nameParts {
+			for i, termVariant := range term {
+				for j, partVariant := range part.variants {
+					weight := part.weight
+					if i > 0 || j > 0 {
+						weight *= weightTransliteration
+					}
+					best = max(best, scoreTerm(termVariant, partVariant)*weight)
+				}
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total / float64(len(terms)), true
+}
+
+func scoreTerm(term, part string) float64 {
+	if term == part {
+		return scoreExact
+	}
+	if strings.HasPrefix(part, term) {
+		return scorePrefix
+	}
+
+	termRunes, partRunes := []rune(term), []rune(part)
+	allowedEdits := 2
+	if len(termRunes) <= 3 {
+		return 0
+	} else if len(termRunes) <= 6 {
+		allowedEdits = 1
+	}
+
+	if edits := editDistance(termRunes, partRunes); edits <= allowedEdits {
+		return scoreTypo - float64(edits-1)*scorePerEdit
+	}
+	// Allow typos in prefixes as well, e.g. "Johm" for "Johannes"
+	if len(partRunes) > len(termRunes) {
+		if edits := editDistance(termRunes, partRunes[:len(termRunes)]); edits <= allowedEdits {
+			return scoreTypo - float64(edits)*scorePerEdit
+		}
+	}
+	return 0
+}
+
+// nameVariants splits the normalized name into its parts, each followed by its folded spelling if there is one
+func nameVariants(name string) [][]string {
+	parts := strings.Fields(normalizeName(name))
+	variants := make([][]string, 0, len(parts))
+	for _, part := range parts {
+		if folded := umlautTransliterations.Replace(part); folded != part {
+			variants = append(variants, []string{part, folded})
+		} else {
+			variants = append(variants, []string{part})
+		}
+	}
+	return variants
+}
+
+// normalizeName lowercases the name and strips all diacritics, which turns umlauts into their base letter
+func normalizeName(name string) string {
+	decomposed := norm.NFD.String(strings.ToLower(name))
+	var sb strings.Builder
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.ReplaceAll(sb.String(), "ß", "ss")
+}
+
+// editDistance is the optimal string alignment distance, i.e. Levenshtein with transpositions of adjacent runes
+func editDistance(a, b []rune) int {
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return prev[len(b)]
+}
@@ -2,60 +2,234 @@ package service
 
 import (
 	"database/sql"
+	goerrors "errors"
+	"log"
+	"strconv"
+	"time"
 
 	"github.com/Sakrafux/family-tree-app/backend/internal/db"
 	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
 	"github.com/Sakrafux/family-tree-app/backend/internal/security"
+	"github.com/google/uuid"
 )
 
+// REFRESH_TOKEN_REUSE_GRACE is how long a rotated refresh token is still accepted, as parallel requests or other tabs
+// may use it before they see the rotated one
+const REFRESH_TOKEN_REUSE_GRACE = 10 * time.Second
+
 type SecurityService struct {
-	db *sql.DB
+	db    *sql.DB
+	audit *AuditLog
 }
 
-func NewSecurityService(db *sql.DB) *SecurityService {
-	return &SecurityService{db: db}
+func NewSecurityService(db *sql.DB, audit *AuditLog) *SecurityService {
+	return &SecurityService{db: db, audit: audit}
 }
 
-func (s *SecurityService) Login(username, password string) (string, string, error) {
+// Login tracks failed attempts per username and per address of the client, which lock further attempts
+func (s *SecurityService) Login(username, password, ip string) (string, string, error) {
+	userKey, ipKey := userLockoutKey(username), ipLockoutKey(ip)
+	if err := s.checkLoginLockout(userKey, ipKey); err != nil {
+		s.auditLoginFailure(username, ip, "locked")
+		return "", "", err
+	}
+
 	user, err := db.GetUser(s.db, username, password)
 	if err != nil {
-		return "", "", errors.NewUnauthorizedError(err.Error())
+		s.auditLoginFailure(username, ip, "invalid credentials")
+		if err := s.recordLoginFailure(userKey, USER_LOGIN_ATTEMPTS); err != nil {
+			return "", "", err
+		}
+		if err := s.recordLoginFailure(ipKey, IP_LOGIN_ATTEMPTS); err != nil {
+			return "", "", err
+		}
+		return "", "", errors.NewUnauthorizedError("invalid username or password")
+	}
+	if err := db.DeleteLoginAttempts(s.db, userKey); err != nil {
+		return "", "", errors.NewInternalServerError(err.Error())
+	}
+	if user.IsDisabled {
+		s.auditLoginFailure(username, ip, "disabled")
+		return "", "", errors.NewUnauthorizedError("Account is disabled")
 	}
 
-	tokenData := &security.TokenData{Id: user.Id, Role: user.Role, NodeId: user.NodeId}
-	refreshToken, err := security.CreateRefreshToken(tokenData)
+	family := uuid.NewString()
+	s.audit.record(user.Username, AUDIT_ACTION_LOGIN, AUDIT_ENTITY_SESSION, family, nil, map[string]string{"Ip": ip})
+	return s.createTokens(user, family)
+}
+
+func (s *SecurityService) auditLoginFailure(username, ip, reason string) {
+	s.audit.record(username, AUDIT_ACTION_LOGIN_FAILED, AUDIT_ENTITY_SESSION, "", nil,
+		map[string]string{"Ip": ip, "Reason": reason})
+}
+
+// RefreshTokens rotates the refresh token, so that every refresh token can only be used once. Using one a second
+// time after REFRESH_TOKEN_REUSE_GRACE means that it has been stolen, so the whole session is revoked, which also
+// logs out the attacker.
+func (s *SecurityService) RefreshTokens(refreshToken string) (string, string, error) {
+	token, err := security.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return "", "", errors.NewUnauthorizedError(err.Error())
+	}
+	jti, err := security.ExtractTokenId(token)
 	if err != nil {
+		return "", "", errors.NewUnauthorizedError(err.Error())
+	}
+
+	family, err := db.UseRefreshToken(s.db, jti)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		if family, err = s.reuseRefreshToken(jti); err != nil {
+			return "", "", err
+		}
+	} else if err != nil {
 		return "", "", errors.NewInternalServerError(err.Error())
 	}
-	accessToken, err := security.CreateAccessToken(tokenData)
+
+	userID, _, _, err := security.ExtractUserData(token)
 	if err != nil {
 		return "", "", errors.NewInternalServerError(err.Error())
 	}
 
-	return refreshToken, accessToken, nil
+	// Admins may have changed or disabled the account since the token was issued
+	user, err := db.GetUserById(s.db, userID)
+	if err != nil {
+		return "", "", errors.NewUnauthorizedError(err.Error())
+	}
+	if user.IsDisabled {
+		return "", "", errors.NewUnauthorizedError("Account is disabled")
+	}
+
+	return s.createTokens(user, family)
 }
 
-func (s *SecurityService) RefreshTokens(refreshToken string) (string, string, error) {
+// reuseRefreshToken returns the family of a token which has just been rotated, and otherwise revokes the family
+func (s *SecurityService) reuseRefreshToken(jti string) (string, error) {
+	stored, err := db.GetRefreshToken(s.db, jti)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return "", errors.NewUnauthorizedError("Unknown refresh token")
+	}
+	if err != nil {
+		return "", errors.NewInternalServerError(err.Error())
+	}
+	if stored.IsRevoked {
+		return "", errors.NewUnauthorizedError("Refresh token has been revoked")
+	}
+	if stored.UsedAt != nil && time.Since(*stored.UsedAt) < REFRESH_TOKEN_REUSE_GRACE {
+		return stored.Family, nil
+	}
+
+	log.Printf("[security] Refresh token of user %d has been reused, revoking its session", stored.UserId)
+	if err := db.RevokeRefreshTokenFamily(s.db, stored.Family); err != nil {
+		return "", errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(s.usernameOf(stored.UserId), AUDIT_ACTION_REFRESH_TOKEN_REUSED, AUDIT_ENTITY_SESSION, stored.Family, nil, nil)
+	return "", errors.NewUnauthorizedError("Refresh token has already been used")
+}
+
+// Logout revokes the session of the refresh token, where invalid tokens are ignored as there is nothing to revoke
+func (s *SecurityService) Logout(refreshToken string) error {
 	token, err := security.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	jti, err := security.ExtractTokenId(token)
+	if err != nil {
+		return nil
+	}
+
+	stored, err := db.GetRefreshToken(s.db, jti)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	if err := db.RevokeRefreshTokenFamily(s.db, stored.Family); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(s.usernameOf(stored.UserId), AUDIT_ACTION_LOGOUT, AUDIT_ENTITY_SESSION, stored.Family, nil, nil)
+	return nil
+}
+
+// ChangePassword revokes all sessions of the user, but returns new tokens for the current one
+func (s *SecurityService) ChangePassword(username, oldPassword, newPassword string) (string, string, error) {
+	user, err := db.GetUser(s.db, username, oldPassword)
 	if err != nil {
 		return "", "", errors.NewUnauthorizedError(err.Error())
 	}
+	if err := validatePassword(newPassword); err != nil {
+		return "", "", err
+	}
+
+	if err := s.setPassword(user.Id, newPassword); err != nil {
+		return "", "", err
+	}
+	s.audit.record(user.Username, AUDIT_ACTION_PASSWORD_CHANGE, AUDIT_ENTITY_USER, strconv.Itoa(user.Id), nil, nil)
+
+	return s.createTokens(user, uuid.NewString())
+}
+
+// ResetPassword redeems a one-time token created by an admin, after which the user has to log in again
+func (s *SecurityService) ResetPassword(token, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	userId, err := db.ConsumePasswordResetToken(s.db, security.HashToken(token))
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return errors.NewUnauthorizedError("Invalid or expired password reset token")
+	}
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	if err := s.setPassword(userId, newPassword); err != nil {
+		return err
+	}
+	s.audit.record(s.usernameOf(userId), AUDIT_ACTION_PASSWORD_RESET, AUDIT_ENTITY_USER, strconv.Itoa(userId), nil, nil)
+	return nil
+}
 
-	userID, role, nodeId, err := security.ExtractUserData(token)
+// usernameOf is only used for auditing actions that are not authenticated by an access token
+func (s *SecurityService) usernameOf(userId int) string {
+	user, err := db.GetUserById(s.db, userId)
 	if err != nil {
-		return "", "", errors.NewInternalServerError(err.Error())
+		return ""
 	}
-	tokenData := &security.TokenData{Id: userID, Role: role, NodeId: nodeId}
+	return user.Username
+}
 
-	rt, err := security.CreateRefreshToken(tokenData)
+func (s *SecurityService) setPassword(userId int, password string) error {
+	salt := security.GenerateSalt()
+	hashed := security.HashPassword(password, salt)
+	if err := db.UpdateUserPassword(s.db, userId, string(hashed), string(salt)); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if err := db.RevokeAllRefreshTokens(s.db, userId); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// createTokens issues a new refresh token within the given session family, which is stored for rotation
+func (s *SecurityService) createTokens(user *db.User, family string) (string, string, error) {
+	tokenData := &security.TokenData{Id: user.Id, Role: user.Role, NodeId: user.NodeId}
+
+	jti := uuid.NewString()
+	err := db.InsertRefreshToken(s.db, jti, user.Id, family, time.Now().Add(security.REFRESH_TOKEN_VALIDITY))
 	if err != nil {
 		return "", "", errors.NewInternalServerError(err.Error())
 	}
 
-	at, err := security.CreateAccessToken(tokenData)
+	refreshToken, err := security.CreateRefreshToken(tokenData, jti)
+	if err != nil {
+		return "", "", errors.NewInternalServerError(err.Error())
+	}
+	accessToken, err := security.CreateAccessToken(tokenData)
 	if err != nil {
 		return "", "", errors.NewInternalServerError(err.Error())
 	}
 
-	return rt, at, nil
+	return refreshToken, accessToken, nil
 }
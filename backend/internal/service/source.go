@@ -0,0 +1,395 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	SOURCE_TYPE_ARCHIVE  = "archive"
+	SOURCE_TYPE_BOOK     = "book"
+	SOURCE_TYPE_URL      = "url"
+	SOURCE_TYPE_REGISTER = "register"
+	SOURCE_TYPE_OTHER    = "other"
+)
+
+var sourceTypes = []string{SOURCE_TYPE_ARCHIVE, SOURCE_TYPE_BOOK, SOURCE_TYPE_URL, SOURCE_TYPE_REGISTER, SOURCE_TYPE_OTHER}
+
+// The quality of a citation follows the certainty assessment of GEDCOM, from 0 for unreliable evidence to 3 for
+// direct and primary evidence
+const (
+	CITATION_QUALITY_MIN = 0
+	CITATION_QUALITY_MAX = 3
+)
+
+func (s *FamilyTreeService) GetAllSources() ([]*db.Source, error) {
+	sources, err := db.GetAllSources(s.pool)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+
+	slices.SortFunc(sources, func(a, b *db.Source) int {
+		return cmp.Compare(a.Title, b.Title)
+	})
+
+	return sources, nil
+}
+
+func (s *FamilyTreeService) GetSource(id uuid.UUID) (*db.Source, error) {
+	return s.getSource(id)
+}
+
+func (s *FamilyTreeService) CreateSource(req *PostSourceRequest, actingUsername string) (*db.Source, error) {
+	source := req.Source
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	source.Id = id
+
+	normalizeSource(&source)
+	if err := validateSource(&source); err != nil {
+		return nil, err
+	}
+
+	created, err := db.CreateSource(s.pool, &source)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_SOURCE, created.Id.String(), nil, created)
+
+	return created, nil
+}
+
+// UpdateSource applies the JSON patch just like UpdatePerson
+func (s *FamilyTreeService) UpdateSource(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Source, error) {
+	var before json.RawMessage
+	var updated *db.Source
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		source, err := tx.getSource(id)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(source))
+
+		if err := json.Unmarshal(patch, source); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		source.Id = id
+
+		normalizeSource(source)
+		if err := validateSource(source); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdateSource(tx.pool, source)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_SOURCE, id.String(), before, updated)
+
+	return updated, nil
+}
+
+// DeleteSource refuses to delete sources which are still cited, as the evidence would silently get lost. The check
+// runs within the same transaction as the deletion, so that no citation can be added in between.
+func (s *FamilyTreeService) DeleteSource(id uuid.UUID, actingUsername string) error {
+	var source *db.Source
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		source, err = tx.getSource(id)
+		if err != nil {
+			return err
+		}
+
+		citations, err := db.GetCitationsBySourceId(tx.pool, id)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if len(citations) > 0 {
+			return errors.NewConflictError(fmt.Sprintf("'%s' is still cited %d times", id, len(citations)))
+		}
+
+		if err := db.DeleteSource(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_SOURCE, id.String(), source, nil)
+
+	return nil
+}
+
+func (s *FamilyTreeService) CreatePersonCitation(personId uuid.UUID, req *PostCitationRequest, actingUsername string) (*db.Citation, error) {
+	return s.createCitation(req, AUDIT_ENTITY_PERSON, personId.String(), actingUsername, func(tx *FamilyTreeService) error {
+		_, err := tx.getPerson(personId)
+		return err
+	}, func(tx *FamilyTreeService, citation *db.Citation) (*db.Citation, error) {
+		return db.CreatePersonCitation(tx.pool, citation, personId)
+	})
+}
+
+func (s *FamilyTreeService) CreateEventCitation(eventId uuid.UUID, req *PostCitationRequest, actingUsername string) (*db.Citation, error) {
+	return s.createCitation(req, AUDIT_ENTITY_EVENT, eventId.String(), actingUsername, func(tx *FamilyTreeService) error {
+		_, err := tx.getEvent(eventId)
+		return err
+	}, func(tx *FamilyTreeService, citation *db.Citation) (*db.Citation, error) {
+		return db.CreateEventCitation(tx.pool, citation, eventId)
+	})
+}
+
+func (s *FamilyTreeService) CreateParentRelationCitation(parentId, childId uuid.UUID, req *PostCitationRequest, actingUsername string) (*db.Citation, error) {
+	subjectId := auditEntityId(parentId.String(), childId.String())
+	return s.createCitation(req, AUDIT_ENTITY_PARENT_RELATION, subjectId, actingUsername, func(tx *FamilyTreeService) error {
+		relation, err := db.GetParentRelation(tx.pool, parentId, childId)
+		if err != nil {
+			return kuzuError(err)
+		}
+		if relation == nil {
+			return errors.NewNotFoundError(fmt.Sprintf("'%s' is not a parent of '%s'", parentId, childId))
+		}
+		return nil
+	}, func(tx *FamilyTreeService, citation *db.Citation) (*db.Citation, error) {
+		return db.CreateRelationCitation(tx.pool, citation, parentId, childId)
+	})
+}
+
+func (s *FamilyTreeService) CreateMarriageCitation(person1Id, person2Id uuid.UUID, req *PostCitationRequest, actingUsername string) (*db.Citation, error) {
+	subjectId := marriageEntityId(person1Id, person2Id)
+	return s.createCitation(req, AUDIT_ENTITY_MARRIAGE_RELATION, subjectId, actingUsername, func(tx *FamilyTreeService) error {
+		_, err := tx.getMarriageRelation(person1Id, person2Id)
+		return err
+	}, func(tx *FamilyTreeService, citation *db.Citation) (*db.Citation, error) {
+		return db.CreateRelationCitation(tx.pool, citation, person1Id, person2Id)
+	})
+}
+
+// createCitation checks the subject and validates the citation, before create links it to the subject. All of it
+// runs within one transaction, so that neither the subject nor the source can be deleted in between.
+func (s *FamilyTreeService) createCitation(req *PostCitationRequest, subjectType, subjectId, actingUsername string, check func(tx *FamilyTreeService) error, create func(tx *FamilyTreeService, citation *db.Citation) (*db.Citation, error)) (*db.Citation, error) {
+	citation := req.Citation
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	citation.Id = id
+	citation.SubjectType = subjectType
+	citation.SubjectId = subjectId
+
+	normalizeCitation(&citation)
+
+	var created *db.Citation
+	err = s.transaction(func(tx *FamilyTreeService) error {
+		if err := check(tx); err != nil {
+			return err
+		}
+		if err := tx.validateCitation(&citation); err != nil {
+			return err
+		}
+
+		created, err = create(tx, &citation)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_CITATION, created.Id.String(), nil, created)
+
+	return created, nil
+}
+
+// UpdateCitation applies the JSON patch just like UpdatePerson, but the citation always stays with its subject
+func (s *FamilyTreeService) UpdateCitation(id uuid.UUID, patch json.RawMessage, actingUsername string) (*db.Citation, error) {
+	var before json.RawMessage
+	var updated *db.Citation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		citation, err := tx.getCitation(id)
+		if err != nil {
+			return err
+		}
+		before = json.RawMessage(marshalAuditValue(citation))
+		subjectType, subjectId := citation.SubjectType, citation.SubjectId
+
+		if err := json.Unmarshal(patch, citation); err != nil {
+			return errors.NewUnprocessableEntityError(err.Error())
+		}
+		citation.Id = id
+		citation.SubjectType, citation.SubjectId = subjectType, subjectId
+
+		normalizeCitation(citation)
+		if err := tx.validateCitation(citation); err != nil {
+			return err
+		}
+
+		updated, err = db.UpdateCitation(tx.pool, citation)
+		if err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_CITATION, id.String(), before, updated)
+
+	return updated, nil
+}
+
+func (s *FamilyTreeService) DeleteCitation(id uuid.UUID, actingUsername string) error {
+	var citation *db.Citation
+	err := s.transaction(func(tx *FamilyTreeService) error {
+		var err error
+		citation, err = tx.getCitation(id)
+		if err != nil {
+			return err
+		}
+
+		if err := db.DeleteCitation(tx.pool, id); err != nil {
+			return kuzuError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_CITATION, id.String(), citation, nil)
+
+	return nil
+}
+
+func (s *FamilyTreeService) getSource(id uuid.UUID) (*db.Source, error) {
+	source, err := db.GetSourceById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if source == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Source '%s' not found", id))
+	}
+	return source, nil
+}
+
+func (s *FamilyTreeService) getCitation(id uuid.UUID) (*db.Citation, error) {
+	citation, err := db.GetCitationById(s.pool, id)
+	if err != nil {
+		return nil, kuzuError(err)
+	}
+	if citation == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Citation '%s' not found", id))
+	}
+	return citation, nil
+}
+
+// normalizeSource trims the texts and treats empty strings as missing values
+func normalizeSource(source *db.Source) {
+	source.Type = strings.ToLower(strings.TrimSpace(source.Type))
+	source.Title = strings.TrimSpace(source.Title)
+	for _, field := range []**string{&source.Author, &source.Repository, &source.Url} {
+		if *field == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(**field)
+		if len(trimmed) == 0 {
+			*field = nil
+		} else {
+			*field = &trimmed
+		}
+	}
+}
+
+func validateSource(source *db.Source) error {
+	if !lo.Contains(sourceTypes, source.Type) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("invalid Type '%s'", source.Type))
+	}
+	if len(source.Title) == 0 {
+		return errors.NewUnprocessableEntityError("Title must not be empty")
+	}
+	return nil
+}
+
+// normalizeCitation trims the texts and treats empty strings as missing values
+func normalizeCitation(citation *db.Citation) {
+	for _, field := range []**string{&citation.Page, &citation.Transcription} {
+		if *field == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(**field)
+		if len(trimmed) == 0 {
+			*field = nil
+		} else {
+			*field = &trimmed
+		}
+	}
+}
+
+func (s *FamilyTreeService) validateCitation(citation *db.Citation) error {
+	if citation.Quality != nil && (*citation.Quality < CITATION_QUALITY_MIN || *citation.Quality > CITATION_QUALITY_MAX) {
+		return errors.NewUnprocessableEntityError(
+			fmt.Sprintf("Quality must be between %d and %d", CITATION_QUALITY_MIN, CITATION_QUALITY_MAX))
+	}
+
+	source, err := db.GetSourceById(s.pool, citation.SourceId)
+	if err != nil {
+		return kuzuError(err)
+	}
+	if source == nil {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("SourceId '%s' is not a known source", citation.SourceId))
+	}
+
+	return nil
+}
+
+// relateCitations assigns the citations to the persons they concern, i.e. citations of relations to both persons
+// and citations of events to the person of the event. Thus, it requires the events to be related already.
+func relateCitations(dto *FamilyTreeDto, chCitations chan []*db.Citation) {
+	eventPersons := make(map[string]uuid.UUID)
+	for _, person := range dto.Persons {
+		person.Citations = make([]*db.Citation, 0)
+		for _, event := range person.Events {
+			eventPersons[event.Id.String()] = person.Id
+		}
+	}
+
+	for _, citation := range <-chCitations {
+		for _, personId := range citedPersonIds(citation, eventPersons) {
+			if person, ok := dto.Persons[personId]; ok {
+				person.Citations = append(person.Citations, citation)
+			}
+		}
+	}
+}
+
+func citedPersonIds(citation *db.Citation, eventPersons map[string]uuid.UUID) []uuid.UUID {
+	if citation.SubjectType == AUDIT_ENTITY_EVENT {
+		if personId, ok := eventPersons[citation.SubjectId]; ok {
+			return []uuid.UUID{personId}
+		}
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0)
+	for _, part := range strings.Split(citation.SubjectId, "/") {
+		if id, err := uuid.Parse(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
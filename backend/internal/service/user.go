@@ -0,0 +1,237 @@
+package service
+
+import (
+	"database/sql"
+	goerrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
+	"github.com/Sakrafux/family-tree-app/backend/internal/security"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const MIN_PASSWORD_LENGTH = 8
+const PASSWORD_RESET_TOKEN_VALIDITY = 72 * time.Hour
+
+type UserService struct {
+	db    *sql.DB
+	pool  *db.KuzuPool
+	audit *AuditLog
+}
+
+func NewUserService(db *sql.DB, pool *db.KuzuPool, audit *AuditLog) *UserService {
+	return &UserService{db: db, pool: pool, audit: audit}
+}
+
+func (s *UserService) GetAllUsers() ([]*UserDto, error) {
+	users, err := db.SelectAllUsers(s.db)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	dtos := lo.Map(users, func(item *db.User, index int) *UserDto {
+		return toUserDto(item)
+	})
+
+	return dtos, nil
+}
+
+func (s *UserService) CreateUser(request PostUserRequest, actingUsername string) (*UserDto, error) {
+	if err := validatePassword(request.Password); err != nil {
+		return nil, err
+	}
+
+	salt := security.GenerateSalt()
+	user := &db.User{
+		Username: strings.TrimSpace(request.Username),
+		Password: string(security.HashPassword(request.Password, salt)),
+		Salt:     string(salt),
+		Role:     request.Role,
+		NodeId:   request.NodeId,
+	}
+	if err := s.validateUser(user); err != nil {
+		return nil, err
+	}
+
+	created, err := db.InsertUser(s.db, user)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	dto := toUserDto(created)
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_USER, strconv.Itoa(dto.Id), nil, dto)
+
+	return dto, nil
+}
+
+// UpdateUser applies the present fields of the request, where admins can neither demote nor disable themselves, so
+// that there is always at least one admin left
+func (s *UserService) UpdateUser(id int, request PatchUserRequest, actingUsername string) (*UserDto, error) {
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	isSelf := user.Username == actingUsername
+	before := toUserDto(user)
+
+	if request.Username != nil {
+		user.Username = strings.TrimSpace(*request.Username)
+	}
+	if request.Role != nil {
+		if isSelf && *request.Role != user.Role {
+			return nil, errors.NewConflictError("Cannot change the role of your own account")
+		}
+		user.Role = *request.Role
+	}
+	if request.NodeId != nil {
+		user.NodeId = *request.NodeId
+	}
+	if request.IsDisabled != nil {
+		if isSelf && *request.IsDisabled {
+			return nil, errors.NewConflictError("Cannot disable your own account")
+		}
+		user.IsDisabled = *request.IsDisabled
+	}
+	if err := s.validateUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := db.UpdateUser(s.db, user); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if user.IsDisabled {
+		if err := db.RevokeAllRefreshTokens(s.db, user.Id); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+	dto := toUserDto(user)
+	s.audit.record(actingUsername, AUDIT_ACTION_UPDATE, AUDIT_ENTITY_USER, strconv.Itoa(id), before, dto)
+
+	return dto, nil
+}
+
+func (s *UserService) DeleteUser(id int, actingUsername string) error {
+	user, err := s.getUser(id)
+	if err != nil {
+		return err
+	}
+	if user.Username == actingUsername {
+		return errors.NewConflictError("Cannot delete your own account")
+	}
+
+	if err := db.DeleteUser(s.db, id); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_USER, strconv.Itoa(id), toUserDto(user), nil)
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere once their access tokens expire
+func (s *UserService) RevokeAllSessions(id int, actingUsername string) error {
+	if _, err := s.getUser(id); err != nil {
+		return err
+	}
+
+	if err := db.RevokeAllRefreshTokens(s.db, id); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_SESSION, strconv.Itoa(id), nil, nil)
+	return nil
+}
+
+// UnlockUser forgets all failed logins of the user, while locked addresses have to wait for their lockout
+func (s *UserService) UnlockUser(id int, actingUsername string) error {
+	user, err := s.getUser(id)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteLoginAttempts(s.db, userLockoutKey(user.Username)); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	s.audit.record(actingUsername, AUDIT_ACTION_DELETE, AUDIT_ENTITY_LOGIN_LOCKOUT, strconv.Itoa(id), nil, nil)
+	return nil
+}
+
+// CreatePasswordResetToken returns a one-time token, which the admin hands to the user to set a new password via
+// the security API. Only the hash of the token is stored.
+func (s *UserService) CreatePasswordResetToken(id int, actingUsername string) (*PasswordResetTokenDto, error) {
+	if _, err := s.getUser(id); err != nil {
+		return nil, err
+	}
+
+	token := security.GenerateToken()
+	expiresAt := time.Now().Add(PASSWORD_RESET_TOKEN_VALIDITY).Truncate(time.Second)
+	if err := db.InsertPasswordResetToken(s.db, id, security.HashToken(token), expiresAt); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	// The token itself must not end up in the audit log
+	s.audit.record(actingUsername, AUDIT_ACTION_CREATE, AUDIT_ENTITY_PASSWORD_RESET, strconv.Itoa(id), nil,
+		map[string]time.Time{"ExpiresAt": expiresAt})
+
+	return &PasswordResetTokenDto{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (s *UserService) getUser(id int) (*db.User, error) {
+	user, err := db.GetUserById(s.db, id)
+	if goerrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("User '%d' not found", id))
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return user, nil
+}
+
+// validateUser checks the role, that the name is unique and that the user is linked to an existing person
+func (s *UserService) validateUser(user *db.User) error {
+	if user.Username == "" {
+		return errors.NewUnprocessableEntityError("Username must not be empty")
+	}
+	if !security.IsValidRole(user.Role) {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("Unknown role '%s'", user.Role))
+	}
+
+	existing, err := db.GetUserByName(s.db, user.Username)
+	if err != nil && !goerrors.Is(err, sql.ErrNoRows) {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if existing != nil && existing.Id != user.Id {
+		return errors.NewConflictError(fmt.Sprintf("Username '%s' is already taken", user.Username))
+	}
+
+	nodeId, err := uuid.Parse(user.NodeId)
+	if err != nil {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("Invalid node '%s'", user.NodeId))
+	}
+	person, err := db.GetPersonById(s.pool, nodeId)
+	if err != nil {
+		return kuzuError(err)
+	}
+	if person == nil {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("Person '%s' not found", nodeId))
+	}
+
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < MIN_PASSWORD_LENGTH {
+		return errors.NewUnprocessableEntityError(fmt.Sprintf("Password must have at least %d characters", MIN_PASSWORD_LENGTH))
+	}
+	return nil
+}
+
+func toUserDto(user *db.User) *UserDto {
+	return &UserDto{
+		Id:         user.Id,
+		Username:   user.Username,
+		Role:       user.Role,
+		NodeId:     user.NodeId,
+		IsDisabled: user.IsDisabled,
+	}
+}
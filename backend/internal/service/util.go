@@ -1,13 +1,44 @@
 package service
 
 import (
-	"cmp"
+	goerrors "errors"
 	"math"
 	"sync"
 
+	"github.com/Sakrafux/family-tree-app/backend/internal/dates"
+	"github.com/Sakrafux/family-tree-app/backend/internal/db"
+	"github.com/Sakrafux/family-tree-app/backend/internal/errors"
 	"github.com/google/uuid"
 )
 
+// kuzuError maps a failed kuzu statement to an HTTP error, where an exhausted connection pool is only temporary
+func kuzuError(err error) error {
+	if goerrors.Is(err, db.ErrPoolTimeout) {
+		return errors.NewServiceUnavailableError(err.Error())
+	}
+	return errors.NewInternalServerError(err.Error())
+}
+
+// transaction runs fn on a copy of the service whose kuzu statements all belong to one write transaction, so that its
+// checks still hold when its writes are committed. Errors of fn are passed on unchanged. Audit entries and revisions
+// are meant to be recorded after the commit, as they cannot be rolled back.
+func (s *FamilyTreeService) transaction(fn func(tx *FamilyTreeService) error) error {
+	var fnErr error
+	err := s.pool.Transaction(func(pool *db.KuzuPool) error {
+		tx := *s
+		tx.pool = pool
+		fnErr = fn(&tx)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return kuzuError(err)
+	}
+	return nil
+}
+
 func initAsync(n int) (*sync.WaitGroup, chan error) {
 	chErr := make(chan error, n)
 	var wg sync.WaitGroup
@@ -38,6 +69,13 @@ func derefDateInt32(p *int32) int32 {
 	return *p
 }
 
+func derefString(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
 func compareByBirthDate(dto *FamilyTreeDto, a, b uuid.UUID) int {
 	personA, ok := dto.Persons[a]
 	if !ok {
@@ -48,16 +86,5 @@ func compareByBirthDate(dto *FamilyTreeDto, a, b uuid.UUID) int {
 		return 0
 	}
 
-	ay, by := derefDateInt32(personA.BirthDateYear), derefDateInt32(personB.BirthDateYear)
-	if ay != by {
-		return cmp.Compare(ay, by)
-	}
-
-	am, bm := derefDateInt32(personA.BirthDateMonth), derefDateInt32(personB.BirthDateMonth)
-	if am != bm {
-		return cmp.Compare(am, bm)
-	}
-
-	ad, bd := derefDateInt32(personA.BirthDateDay), derefDateInt32(personB.BirthDateDay)
-	return cmp.Compare(ad, bd)
+	return dates.Compare(personA.BirthDate(), personB.BirthDate())
 }
@@ -5,6 +5,7 @@ import {
     useContext,
     useMemo,
     useReducer,
+    useRef,
 } from "react";
 
 import { createApi } from "@/api/ApiProvider";
@@ -105,34 +106,47 @@ export function AuthProvider({ children }: PropsWithChildren) {
         [api],
     );
 
-    const refresh = useCallback(async () => {
-        dispatch({ type: AuthActions.START });
-        try {
-            const rawData = await api
-                .get<AccessTokenDto>("/security/token")
-                .then((res) => res.data);
-            const jwt = parseJwt(rawData.AccessToken);
-            const data = {
-                token: rawData.AccessToken,
-                expiresAt: new Date(jwt.payload.exp * 1000),
-                role: jwt.payload.role,
-                nodeId: jwt.payload["node_id"],
-            };
-
-            dispatch({ type: AuthActions.RESULT, payload: data });
-            localStorage.setItem("family_tree-auth_token", rawData.AccessToken);
-
-            return data;
-        } catch (err) {
-            dispatch({ type: AuthActions.ERROR, error: err });
+    // Parallel requests share one refresh, as every refresh token can only be used once
+    const pendingRefresh = useRef<Promise<AuthData | undefined> | undefined>(undefined);
+
+    const refresh = useCallback(() => {
+        if (!pendingRefresh.current) {
+            pendingRefresh.current = (async () => {
+                dispatch({ type: AuthActions.START });
+                try {
+                    const rawData = await api
+                        .get<AccessTokenDto>("/security/token")
+                        .then((res) => res.data);
+                    const jwt = parseJwt(rawData.AccessToken);
+                    const data = {
+                        token: rawData.AccessToken,
+                        expiresAt: new Date(jwt.payload.exp * 1000),
+                        role: jwt.payload.role,
+                        nodeId: jwt.payload["node_id"],
+                    };
+
+                    dispatch({ type: AuthActions.RESULT, payload: data });
+                    localStorage.setItem("family_tree-auth_token", rawData.AccessToken);
+
+                    return data;
+                } catch (err) {
+                    dispatch({ type: AuthActions.ERROR, error: err });
+                }
+            })().finally(() => {
+                pendingRefresh.current = undefined;
+            });
         }
+        return pendingRefresh.current;
     }, [api]);
 
     const logout = useCallback(async () => {
         dispatch({ type: AuthActions.RESULT, payload: undefined });
 
         localStorage.removeItem("family_tree-auth_token");
-    }, []);
+
+        // Revokes the refresh token, which is inaccessible to the client as an http-only cookie
+        await api.post("/security/logout").catch(() => undefined);
+    }, [api]);
 
     const value = useMemo(
         () => ({ state, login, refresh, logout }),